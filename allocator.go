@@ -0,0 +1,145 @@
+package main
+
+import (
+	"errors"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//candidate is a position at which a vehicle could park
+type candidate struct {
+	slot     int    //First slot the vehicle would occupy
+	slots    int    //Number of slots the vehicle would occupy
+	gapStart int    //First slot of the run of free slots holding the position
+	gapSize  int    //Number of free slots in the run
+	floor    *floor //Floor holding the position
+}
+
+//allocator is a strategy choosing where a vehicle parks
+type allocator interface {
+	//choose picks one of the candidate positions, which are given in ascending slot order
+	choose(candidates []candidate) candidate
+	//String returns the name selecting the strategy in create_parking_lot
+	String() string
+}
+
+//errUnknownStrategy reports an allocation strategy which does not exist
+var errUnknownStrategy = errors.New("Unknown allocation strategy")
+
+//parseStrategy selects an allocation strategy by name:
+//first_fit, best_fit, nearest_exit, nearest_exit:<position of the exit on each floor>, or random
+func parseStrategy(name string) (allocator, error) {
+	switch {
+	case name == "first_fit":
+		return firstFit{}, nil
+	case name == "best_fit":
+		return bestFit{}, nil
+	case name == "nearest_exit":
+		return nearestExit{exit: 1}, nil
+	case strings.HasPrefix(name, "nearest_exit:"):
+		exit, err := strconv.Atoi(strings.TrimPrefix(name, "nearest_exit:"))
+		if err != nil || exit < 1 {
+			return nil, errUnknownStrategy
+		}
+		return nearestExit{exit: exit}, nil
+	case name == "random":
+		return newRandomFit(rand.NewSource(time.Now().UnixNano())), nil
+	}
+	return nil, errUnknownStrategy
+}
+
+//firstFit parks a vehicle in the lowest free position
+type firstFit struct{}
+
+func (firstFit) choose(candidates []candidate) candidate {
+	return candidates[0]
+}
+
+func (firstFit) String() string {
+	return "first_fit"
+}
+
+//lowestFloor returns the candidates on the lowest floor holding any, which come first in ascending slot order
+func lowestFloor(candidates []candidate) []candidate {
+	for ii, candidate := range candidates {
+		if candidate.floor != candidates[0].floor {
+			return candidates[:ii]
+		}
+	}
+	return candidates
+}
+
+//bestFit parks a vehicle in the smallest run of free slots it fits in, leaving larger runs for larger vehicles,
+//filling the lowest floor first
+type bestFit struct{}
+
+func (bestFit) choose(candidates []candidate) candidate {
+	candidates = lowestFloor(candidates)
+	best := candidates[0]
+	for _, candidate := range candidates[1:] {
+		if candidate.gapSize < best.gapSize {
+			best = candidate
+		}
+	}
+	return best
+}
+
+func (bestFit) String() string {
+	return "best_fit"
+}
+
+//nearestExit parks a vehicle as close as possible to the exit, filling the lowest floor first
+type nearestExit struct {
+	exit int //Position of the exit on each floor, counted from the first slot of the floor
+}
+
+func (nearestExit nearestExit) choose(candidates []candidate) candidate {
+	candidates = lowestFloor(candidates)
+	best := candidates[0]
+	for _, candidate := range candidates[1:] {
+		if nearestExit.distance(candidate) < nearestExit.distance(best) {
+			best = candidate
+		}
+	}
+	return best
+}
+
+//distance counts the slots between the exit and the nearest slot the vehicle would occupy
+func (nearestExit nearestExit) distance(candidate candidate) int {
+	exitSlot := candidate.floor.firstSlot + nearestExit.exit - 1
+	switch {
+	case candidate.slot > exitSlot:
+		return candidate.slot - exitSlot
+	case candidate.slot+candidate.slots-1 < exitSlot:
+		return exitSlot - (candidate.slot + candidate.slots - 1)
+	}
+	return 0
+}
+
+func (nearestExit nearestExit) String() string {
+	if nearestExit.exit == 1 {
+		return "nearest_exit"
+	}
+	return "nearest_exit:" + strconv.Itoa(nearestExit.exit)
+}
+
+//randomFit parks a vehicle in a free position on the lowest floor with any, chosen at random
+type randomFit struct {
+	rand *rand.Rand
+}
+
+//newRandomFit creates a random strategy drawing from the given source
+func newRandomFit(source rand.Source) randomFit {
+	return randomFit{rand: rand.New(source)}
+}
+
+func (randomFit randomFit) choose(candidates []candidate) candidate {
+	candidates = lowestFloor(candidates)
+	return candidates[randomFit.rand.Intn(len(candidates))]
+}
+
+func (randomFit) String() string {
+	return "random"
+}
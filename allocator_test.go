@@ -0,0 +1,145 @@
+package main
+
+import (
+	"container/list"
+	"fmt"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+//fragmentedFloor returns a floor of 12 slots whose slots 1, 5, and 8 are occupied and 9 onwards were never filled
+func fragmentedFloor() []*floor {
+	emptySlots := list.New()
+	for _, slotNo := range []int{2, 3, 4, 6, 7} {
+		emptySlots.PushBack(slotNo)
+	}
+	return singleFloor(emptySlots, 8, 12)
+}
+
+func TestCarpark_insertCarWithStrategy(t *testing.T) {
+	tests := []struct {
+		name           string
+		strategy       allocator
+		want           int
+		wantEmptySlots []int
+		wantHighest    int
+	}{
+		{name: "First fit", strategy: firstFit{}, want: 2, wantEmptySlots: []int{4, 6, 7}, wantHighest: 8},
+		{name: "Best fit", strategy: bestFit{}, want: 6, wantEmptySlots: []int{2, 3, 4}, wantHighest: 8},
+		{name: "Nearest to exit at first slot", strategy: nearestExit{exit: 1}, want: 2, wantEmptySlots: []int{4, 6, 7}, wantHighest: 8},
+		{name: "Nearest to exit at last slot", strategy: nearestExit{exit: 12}, want: 11, wantEmptySlots: []int{2, 3, 4, 6, 7, 9, 10}, wantHighest: 12},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			carpark := withIndexes(&Carpark{Map: map[int]Vehicle{}, floors: fragmentedFloor(), strategy: tt.strategy})
+			got, _, err := carpark.insertCar(defaultVehicleTypes.newVehicle("car", "KA-01-HH-9999", "White"))
+			if err != nil {
+				t.Fatalf("Carpark.insertCar() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Carpark.insertCar() = %v, want %v", got, tt.want)
+			}
+			var gotEmptySlots []int
+			for e := carpark.floors[0].emptySlots.Front(); e != nil; e = e.Next() {
+				gotEmptySlots = append(gotEmptySlots, e.Value.(int))
+			}
+			if !reflect.DeepEqual(gotEmptySlots, tt.wantEmptySlots) || carpark.floors[0].highestSlot != tt.wantHighest {
+				t.Errorf("Carpark.insertCar() empty slots = %v, highest slot = %v, want %v, %v",
+					gotEmptySlots, carpark.floors[0].highestSlot, tt.wantEmptySlots, tt.wantHighest)
+			}
+		})
+	}
+}
+
+func TestCarpark_insertCarAcrossHighestSlot(t *testing.T) {
+	//Slots 1 and 2 are occupied, slot 3 was left empty, and slot 4 was never filled
+	emptySlots := list.New()
+	emptySlots.PushBack(3)
+	carpark := withIndexes(&Carpark{Map: map[int]Vehicle{}, floors: singleFloor(emptySlots, 3, 4), strategy: firstFit{}})
+	got, _, err := carpark.insertCar(defaultVehicleTypes.newVehicle("car", "KA-01-HH-9999", "White"))
+	if err != nil {
+		t.Fatalf("Carpark.insertCar() error = %v", err)
+	}
+	if got != 3 || carpark.floors[0].emptySlots.Len() != 0 || carpark.floors[0].highestSlot != 4 {
+		t.Errorf("Carpark.insertCar() = %v, empty slots = %v, highest slot = %v, want 3, 0, 4",
+			got, carpark.floors[0].emptySlots.Len(), carpark.floors[0].highestSlot)
+	}
+}
+
+func TestCarpark_insertCarWithStrategyOnFloors(t *testing.T) {
+	tests := []struct {
+		name     string
+		strategy allocator
+	}{
+		{name: "First fit", strategy: firstFit{}},
+		{name: "Best fit", strategy: bestFit{}},
+		{name: "Nearest to exit", strategy: nearestExit{exit: 10}},
+		{name: "Random", strategy: newRandomFit(rand.NewSource(1))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			//The smaller run of free slots on the upper floor must not draw vehicles off the empty lower floor
+			carpark := &Carpark{}
+			if err := carpark.init(tt.strategy, 10, 4); err != nil {
+				t.Fatal(err)
+			}
+			for ii := 0; ii < 10; ii++ {
+				_, floor, err := carpark.insertCar(defaultVehicleTypes.newVehicle("motorcycle", fmt.Sprintf("KA-01-HH-%04d", ii), "White"))
+				if err != nil {
+					t.Fatalf("Carpark.insertCar() error = %v", err)
+				}
+				if floor != 1 {
+					t.Fatalf("Carpark.insertCar() parked vehicle %v on floor %v, want floor 1 until it is full", ii+1, floor)
+				}
+			}
+		})
+	}
+}
+
+func Test_randomFit_choose(t *testing.T) {
+	candidates := fragmentedFloor()[0].candidates(2)
+	strategy := newRandomFit(rand.NewSource(1))
+	seen := make(map[int]bool)
+	for ii := 0; ii < 100; ii++ {
+		seen[strategy.choose(candidates).slot] = true
+	}
+	for slotNo := range seen {
+		if slotNo != 2 && slotNo != 3 && slotNo != 6 && (slotNo < 9 || slotNo > 11) {
+			t.Errorf("randomFit.choose() = %v, which is not a free position", slotNo)
+		}
+	}
+	if len(seen) < 2 {
+		t.Errorf("randomFit.choose() chose %v positions in 100 draws, want several", len(seen))
+	}
+}
+
+func Test_parseStrategy(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    allocator
+		wantErr bool
+	}{
+		{name: "first_fit", want: firstFit{}},
+		{name: "best_fit", want: bestFit{}},
+		{name: "nearest_exit", want: nearestExit{exit: 1}},
+		{name: "nearest_exit:6", want: nearestExit{exit: 6}},
+		{name: "nearest_exit:0", wantErr: true},
+		{name: "worst_fit", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseStrategy(tt.name)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("parseStrategy() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseStrategy() = %v, want %v", got, tt.want)
+			}
+			if got != nil && got.String() != tt.name {
+				t.Errorf("parseStrategy().String() = %v, want %v", got.String(), tt.name)
+			}
+		})
+	}
+}
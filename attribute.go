@@ -0,0 +1,212 @@
+package main
+
+import (
+	"errors"
+	"strings"
+)
+
+//attributes is a set of slot attributes, also used for the permits and needs of a vehicle
+type attributes uint8
+
+//Attributes a slot may carry
+const (
+	attrAccessible attributes = 1 << iota //Slot kept for vehicles with an accessible permit
+	attrEVCharger                         //Slot with a charger, preferred by electric vehicles
+	attrReserved                          //Slot kept for vehicles with a reserved permit
+	attrEmergency                         //Emergency vehicle, admitted first from the waiting queue
+)
+
+//restricted are the attributes of slots which only vehicles holding the matching permit may use
+const restricted = attrAccessible | attrReserved
+
+//attributeNames names each slot attribute
+var attributeNames = map[string]attributes{
+	"accessible": attrAccessible,
+	"ev_charger": attrEVCharger,
+	"reserved":   attrReserved,
+}
+
+//permitNames names each vehicle permit or need
+var permitNames = map[string]attributes{
+	"accessible": attrAccessible,
+	"ev":         attrEVCharger,
+	"reserved":   attrReserved,
+	"emergency":  attrEmergency,
+}
+
+//Errors reported when setting slot attributes
+var (
+	errUnknownAttribute  = errors.New("Unknown slot attribute")
+	errUnknownPermit     = errors.New("Unknown vehicle permit")
+	errAttributeOccupied = errors.New("Sorry, a vehicle parked in those slots has no permit for that attribute")
+)
+
+//parseAttributes reads names from the given table into a set of attributes
+func parseAttributes(list []string, names map[string]attributes, errUnknown error) (attributes, error) {
+	var set attributes
+	for _, name := range list {
+		attribute, ok := names[strings.ToLower(strings.TrimSpace(name))]
+		if !ok {
+			return 0, errUnknown
+		}
+		set |= attribute
+	}
+	return set, nil
+}
+
+//names lists the names of a set of attributes from the given table, in the order of the attributes
+func (set attributes) names(names map[string]attributes) []string {
+	var list []string
+	for attribute := attrAccessible; attribute <= attrEmergency; attribute <<= 1 {
+		for name, value := range names {
+			if value == attribute && set&attribute != 0 {
+				list = append(list, name)
+			}
+		}
+	}
+	return list
+}
+
+//Give the slots from 'firstSlot' to 'lastSlot' an attribute, refusing when a vehicle parked there has no permit for it
+func (carpark *Carpark) setAttribute(name string, firstSlot int, lastSlot int) error {
+	carpark.mu.Lock()
+	defer carpark.mu.Unlock()
+
+	if err := carpark.initStatus(); err != nil {
+		return err
+	}
+	attribute, ok := attributeNames[strings.ToLower(name)]
+	if !ok {
+		return errUnknownAttribute
+	}
+	if firstSlot > lastSlot || carpark.floorOf(firstSlot) == nil || carpark.floorOf(lastSlot) == nil {
+		return errSlotRange
+	}
+	for slotNo, vehicle := range carpark.Map {
+		last := slotNo + vehicle.getSlotsNeeded() - 1
+		if slotNo <= lastSlot && last >= firstSlot && attribute&restricted&^*vehicle.getPermits() != 0 {
+			return errAttributeOccupied
+		}
+	}
+	err := carpark.record(&event{Type: eventSetAttribute, Time: carpark.now(), Attribute: strings.ToLower(name), Slot: firstSlot, LastSlot: lastSlot})
+	if err != nil {
+		return err
+	}
+	carpark.mark(attribute, firstSlot, lastSlot, true)
+	carpark.changed()
+	return nil
+}
+
+//Take an attribute away from the slots from 'firstSlot' to 'lastSlot', admit waiting vehicles which may now park,
+//and return the vehicles admitted
+func (carpark *Carpark) clearAttribute(name string, firstSlot int, lastSlot int) ([]Vehicle, error) {
+	carpark.mu.Lock()
+	defer carpark.mu.Unlock()
+
+	if err := carpark.initStatus(); err != nil {
+		return nil, err
+	}
+	attribute, ok := attributeNames[strings.ToLower(name)]
+	if !ok {
+		return nil, errUnknownAttribute
+	}
+	if firstSlot > lastSlot || carpark.floorOf(firstSlot) == nil || carpark.floorOf(lastSlot) == nil {
+		return nil, errSlotRange
+	}
+	err := carpark.record(&event{Type: eventClearAttribute, Time: carpark.now(), Attribute: strings.ToLower(name), Slot: firstSlot, LastSlot: lastSlot})
+	if err != nil {
+		return nil, err
+	}
+	carpark.mark(attribute, firstSlot, lastSlot, false)
+	carpark.changed()
+	return carpark.admit(), nil
+}
+
+//Return the free slots carrying an attribute, in ascending order
+func (carpark *Carpark) getFreeSlotsWithAttribute(name string) ([]int, error) {
+	carpark.mu.Lock()
+	defer carpark.mu.Unlock()
+
+	if err := carpark.initStatus(); err != nil {
+		return nil, err
+	}
+	attribute, ok := attributeNames[strings.ToLower(name)]
+	if !ok {
+		return nil, errUnknownAttribute
+	}
+	free := carpark.freeSlots()
+	var slots []int
+	for _, floor := range carpark.floors {
+		for slotNo := floor.firstSlot; slotNo <= floor.maxSlot; slotNo++ {
+			if free[slotNo] && carpark.attributes[slotNo]&attribute != 0 {
+				slots = append(slots, slotNo)
+			}
+		}
+	}
+	if len(slots) == 0 {
+		return nil, errNotFound
+	}
+	return slots, nil
+}
+
+//The following helpers expect the caller to hold the carpark lock
+
+//Give or take away an attribute of the slots from 'firstSlot' to 'lastSlot'
+func (carpark *Carpark) mark(attribute attributes, firstSlot int, lastSlot int, set bool) {
+	if carpark.attributes == nil {
+		carpark.attributes = make(map[int]attributes)
+	}
+	for slotNo := firstSlot; slotNo <= lastSlot; slotNo++ {
+		if set {
+			carpark.attributes[slotNo] |= attribute
+		} else if carpark.attributes[slotNo] &^= attribute; carpark.attributes[slotNo] == 0 {
+			delete(carpark.attributes, slotNo)
+		}
+	}
+}
+
+//Check whether a vehicle holds the permits for every restricted slot of a run
+func (carpark *Carpark) permitted(vehicle Vehicle, slotNo int, slots int) bool {
+	for ii := 0; ii < slots; ii++ {
+		if carpark.attributes[slotNo+ii]&restricted&^*vehicle.getPermits() != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+//Check whether any slot of a run carries an attribute
+func (carpark *Carpark) touches(slotNo int, slots int, attribute attributes) bool {
+	for ii := 0; ii < slots; ii++ {
+		if carpark.attributes[slotNo+ii]&attribute != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+//Narrow the positions to those suiting the vehicle's needs, when any do: electric vehicles go to
+//chargers first and other vehicles keep away from them, and permit holders go to accessible slots first
+func (carpark *Carpark) prefer(vehicle Vehicle, candidates []candidate) []candidate {
+	narrow := func(keep func(candidate candidate) bool) {
+		var kept []candidate
+		for _, candidate := range candidates {
+			if keep(candidate) {
+				kept = append(kept, candidate)
+			}
+		}
+		if len(kept) > 0 {
+			candidates = kept
+		}
+	}
+	ev := *vehicle.getPermits()&attrEVCharger != 0
+	narrow(func(candidate candidate) bool {
+		return carpark.touches(candidate.slot, candidate.slots, attrEVCharger) == ev
+	})
+	if *vehicle.getPermits()&attrAccessible != 0 {
+		narrow(func(candidate candidate) bool {
+			return carpark.touches(candidate.slot, candidate.slots, attrAccessible)
+		})
+	}
+	return candidates
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+//withPermits gives a vehicle permits
+func withPermits(vehicle Vehicle, permits attributes) Vehicle {
+	*vehicle.getPermits() = permits
+	return vehicle
+}
+
+func TestCarpark_insertCarWithAttributes(t *testing.T) {
+	carpark := &Carpark{}
+	carpark.init(nil, 8)
+	carpark.setAttribute("accessible", 1, 2)
+	carpark.setAttribute("ev_charger", 4, 4)
+	carpark.setAttribute("reserved", 8, 8)
+
+	tests := []struct {
+		name    string
+		vehicle Vehicle
+		want    int
+		wantErr error
+	}{
+		{name: "Car keeps out of accessible slots and away from chargers", vehicle: defaultVehicleTypes.newVehicle("car", "KA-01-HH-0001", "White"), want: 5},
+		{name: "Electric car goes to a charger", vehicle: withPermits(defaultVehicleTypes.newVehicle("car", "KA-01-EV-0001", "Blue"), attrEVCharger), want: 3},
+		{name: "Permit holder goes to accessible slots", vehicle: withPermits(defaultVehicleTypes.newVehicle("motorcycle", "KA-01-MM-0001", "Black"), attrAccessible), want: 1},
+		{name: "Motorcycle takes the last unrestricted slot", vehicle: defaultVehicleTypes.newVehicle("motorcycle", "KA-01-MM-0002", "Black"), want: 7},
+		{name: "Reserved slot refused without a permit", vehicle: defaultVehicleTypes.newVehicle("motorcycle", "KA-01-MM-0003", "Black"), wantErr: errFull},
+		{name: "Reserved slot taken with a permit", vehicle: withPermits(defaultVehicleTypes.newVehicle("motorcycle", "KA-01-MM-0004", "Black"), attrReserved), want: 8},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _, err := carpark.insertCar(tt.vehicle)
+			if err != tt.wantErr {
+				t.Fatalf("Carpark.insertCar() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Carpark.insertCar() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCarpark_setAttribute(t *testing.T) {
+	carpark := &Carpark{}
+	carpark.init(nil, 6)
+	carpark.insertCar(defaultVehicleTypes.newVehicle("car", "KA-01-HH-0001", "White"))
+
+	if err := carpark.setAttribute("accessible", 2, 3); err != errAttributeOccupied {
+		t.Errorf("Carpark.setAttribute() error = %v, wantErr %v", err, errAttributeOccupied)
+	}
+	if err := carpark.setAttribute("solar", 3, 4); err != errUnknownAttribute {
+		t.Errorf("Carpark.setAttribute() error = %v, wantErr %v", err, errUnknownAttribute)
+	}
+	if err := carpark.setAttribute("ev_charger", 1, 4); err != nil {
+		t.Fatalf("Carpark.setAttribute() error = %v", err)
+	}
+	if err := carpark.setAttribute("Accessible", 4, 5); err != nil {
+		t.Fatalf("Carpark.setAttribute() error = %v", err)
+	}
+	if _, err := carpark.clearAttribute("ev_charger", 4, 4); err != nil {
+		t.Fatalf("Carpark.clearAttribute() error = %v", err)
+	}
+
+	tests := []struct {
+		attribute string
+		want      []int
+		wantErr   error
+	}{
+		{attribute: "ev_charger", want: []int{3}},
+		{attribute: "accessible", want: []int{4, 5}},
+		{attribute: "reserved", wantErr: errNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.attribute, func(t *testing.T) {
+			got, err := carpark.getFreeSlotsWithAttribute(tt.attribute)
+			if err != tt.wantErr {
+				t.Fatalf("Carpark.getFreeSlotsWithAttribute() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Carpark.getFreeSlotsWithAttribute() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+)
+
+//failure records a command which failed
+type failure struct {
+	line    int      //Line of input on which the command starts
+	command []string //Tokens of the command, nil when it could not be read
+	err     error    //Error the command failed with
+}
+
+//summary counts the commands which succeeded and records those which failed
+type summary struct {
+	succeeded int       //Number of commands which succeeded
+	failures  []failure //Commands which failed, in the order they were read
+}
+
+//add records the outcome of a command, where a vehicle added to the waiting queue counts as a success
+func (summary *summary) add(line int, command []string, err error) {
+	if err == nil {
+		summary.succeeded++
+		return
+	}
+	summary.failures = append(summary.failures, failure{line: line, command: command, err: err})
+}
+
+//failed checks whether any command failed
+func (summary *summary) failed() bool {
+	return len(summary.failures) > 0
+}
+
+//report lists the number of commands which succeeded and failed, and the line and error of each failure
+func (summary *summary) report() *report {
+	out := &report{command: "summary"}
+	out.printf("%v commands succeeded, %v failed", summary.succeeded, len(summary.failures))
+	out.set("succeeded", summary.succeeded)
+	out.set("failed", len(summary.failures))
+	if summary.failed() {
+		var rows [][]string
+		for _, failure := range summary.failures {
+			rows = append(rows, []string{strconv.Itoa(failure.line), strings.Join(failure.command, " "), failure.err.Error()})
+		}
+		out.table([]string{"Line", "Command", "Error"}, rows)
+	}
+	return out
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test_mainBatch(t *testing.T) {
+	//Save old settings before rewriting settings
+	oldArgs := os.Args
+	oldOutStream := outStream
+	oldOsExit := osExit
+	oldOutFormat := outFormat
+	defer func() {
+		os.Args = oldArgs
+		outStream = oldOutStream
+		osExit = oldOsExit
+		outFormat = oldOutFormat
+	}()
+
+	dir, err := ioutil.TempDir("", "batch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	fileName := filepath.Join(dir, "commands.txt")
+	input := "create_parking_lot 4\npark KA-01-HH-1234 White car\nleave 3\npark KA-01-HH-9999 White car\nparked KA-01-HH-7777 Red car\nstatus\n"
+	if err := ioutil.WriteFile(fileName, []byte(input), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		args     []string
+		want     string
+		wantCode int
+	}{
+		{name: "Errors inline",
+			args: []string{"cmd", fileName},
+			want: `Created a parking lot with 4 slots
+Allocated slot number: 1
+Vehicle non-existent in carpark
+Allocated slot number: 3
+Line 5, token 1: unknown command "parked", try help
+Slot No.    Registration No    Colour    Type
+1           KA-01-HH-1234      White     Car
+3           KA-01-HH-9999      White     Car
+`,
+		},
+		{name: "Strict",
+			args: []string{"cmd", "-strict", fileName},
+			want: `Created a parking lot with 4 slots
+Allocated slot number: 1
+Vehicle non-existent in carpark
+`,
+			wantCode: 1,
+		},
+		{name: "Summary",
+			args: []string{"cmd", "-summary", fileName},
+			want: `Created a parking lot with 4 slots
+Allocated slot number: 1
+Vehicle non-existent in carpark
+Allocated slot number: 3
+Line 5, token 1: unknown command "parked", try help
+Slot No.    Registration No    Colour    Type
+1           KA-01-HH-1234      White     Car
+3           KA-01-HH-9999      White     Car
+4 commands succeeded, 2 failed
+Line    Command                         Error
+3       leave 3                         Vehicle non-existent in carpark
+5       parked KA-01-HH-7777 Red car    Line 5, token 1: unknown command "parked", try help
+`,
+			wantCode: 1,
+		},
+		{name: "CSV",
+			args: []string{"cmd", "-format", "csv", fileName},
+			want: `command,floors,slots
+create_parking_lot,1,4
+command,floor,registration,slot
+park,1,KA-01-HH-1234,1
+command,error
+leave,Vehicle non-existent in carpark
+command,floor,registration,slot
+park,1,KA-01-HH-9999,3
+command,error
+parked,"Line 5, token 1: unknown command ""parked"", try help"
+command,Slot No.,Registration No,Colour,Type
+status,1,KA-01-HH-1234,White,Car
+status,3,KA-01-HH-9999,White,Car
+`,
+		},
+		{name: "Summary as JSON",
+			args: []string{"cmd", "-summary", "-format", "json", "-strict", fileName},
+			want: `{"command":"create_parking_lot","messages":["Created a parking lot with 4 slots"],"result":{"floors":1,"slots":4}}
+{"command":"park","messages":["Allocated slot number: 1"],"result":{"floor":1,"registration":"KA-01-HH-1234","slot":1}}
+{"command":"leave","error":"Vehicle non-existent in carpark"}
+{"command":"summary","messages":["2 commands succeeded, 1 failed"],"result":{"failed":1,"succeeded":2},"rows":[{"command":"leave 3","error":"Vehicle non-existent in carpark","line":"3"}]}
+`,
+			wantCode: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotBuf bytes.Buffer
+			outStream = &gotBuf
+			gotCode := 0
+			osExit = func(code int) { gotCode = code }
+			os.Args = tt.args
+			main()
+			if gotBuf.String() != tt.want {
+				t.Errorf("main() = %v, want = %v", gotBuf.String(), tt.want)
+			}
+			if gotCode != tt.wantCode {
+				t.Errorf("main() exit code = %v, want %v", gotCode, tt.wantCode)
+			}
+		})
+	}
+}
@@ -1,117 +1,436 @@
 package main
 
 import (
-	"container/list"
 	"errors"
-	"sortedlist"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 )
 
-//Carpark represents the carpark map, empty slots, and maximum number of slots filled
+//Errors reported by carpark operations
+var (
+	errNotInitialized     = errors.New("Carpark not initialized")
+	errAlreadyInitialized = errors.New("Carpark already initialized")
+	errNoFloors           = errors.New("Carpark needs at least one floor")
+	errEmptyFloor         = errors.New("Each floor needs at least one slot")
+	errUnknownVehicle     = errors.New("Unknown or nil vehicle")
+	errFull               = errors.New("Sorry, parking lot is full")
+	errVehicleNotFound    = errors.New("Vehicle non-existent in carpark")
+	errDuplicate          = errors.New("Vehicle already parked in carpark")
+	errNotFound           = errors.New("Not found")
+)
+
+//Carpark represents the carpark map and the floors holding its slots, and is safe for concurrent use
 type Carpark struct {
-	Map         map[int]Vehicle //Properties of each vehicle parked in the carpark
-	highestSlot int             //Highest number of slots filled throughout carpark operation
-	maxSlot     int             //Maximum number of slots available
-	emptySlots  *list.List      //List containing sorted empty slots in ascending order
+	mu            sync.Mutex              //Guards the carpark state against concurrent gates
+	Map           map[int]Vehicle         //Properties of each vehicle parked in the carpark
+	registrations map[string]int          //Slot of each parked vehicle keyed by compact registration number
+	colours       map[string][]int        //Ascending slots of parked vehicles keyed by normalized colour
+	floors        []*floor                //Floors of the carpark, ordered from the lowest level
+	zones         map[int]string          //Type of each slot which is not a general slot
+	attributes    map[int]attributes      //Attributes of each slot carrying any
+	reservations  map[string]*reservation //Slots held for expected vehicles keyed by compact registration number
+	queue         *waitingQueue           //Vehicles waiting for slots when the carpark is full, nil when vehicles are turned away
+	overflow      map[string][]string     //Further zones each vehicle type may use once its own and general slots are taken
+	strategy      allocator               //Chooses where vehicles park among the free slots, defaults to first fit
+	plates        plateFormat             //Format of registration numbers accepted, defaults to any
+	clock         func() time.Time        //Source of the current time, defaults to the system clock
+	tariffs       *tariffPlan             //Parking charges of each vehicle type, defaults to defaultTariffs
+	vehicleTypes  vehicleRegistry         //Types of vehicle parked, defaults to defaultVehicleTypes
+	store         *stateStore             //Saves the carpark state, nil when the state is not kept
+	events        *eventLog               //Records every change to the carpark, nil when no log is kept
 }
 
-//Initialize carpark parameters
-func (carpark *Carpark) init(maxSlot int) error {
+//Initialize carpark parameters with the allocation strategy, or first fit when nil,
+//and the number of slots on each floor, starting from the lowest floor
+func (carpark *Carpark) init(strategy allocator, layout ...int) error {
+	carpark.mu.Lock()
+	defer carpark.mu.Unlock()
+
 	if err := carpark.initStatus(); err == nil {
-		return errors.New("Carpark already initialized")
+		return errAlreadyInitialized
+	}
+	if err := validLayout(layout); err != nil {
+		return err
+	}
+	if strategy == nil {
+		strategy = firstFit{}
+	}
+	if err := carpark.record(&event{Type: eventCreate, Time: carpark.now(), Layout: layout, Strategy: strategy.String()}); err != nil {
+		return err
+	}
+	carpark.build(layout, strategy)
+	carpark.changed()
+	return nil
+}
+
+//validLayout checks that a carpark has floors and that every floor has slots
+func validLayout(layout []int) error {
+	if len(layout) == 0 {
+		return errNoFloors
+	}
+	for _, slots := range layout {
+		if slots <= 0 {
+			return errEmptyFloor
+		}
 	}
-	carpark.Map = make(map[int]Vehicle) //Setup a map of the carpark
-	carpark.emptySlots = list.New()     //Setup an empty heap of empty parking slots
-	carpark.maxSlot = maxSlot           //Set the maximum number of slots
 	return nil
 }
 
-//Park a vehicle in carpark
-func (carpark *Carpark) insertCar(vehicle Vehicle) (int, error) {
+//Park a vehicle in carpark, filling the lowest floor first, and return its slot and floor numbers.
+//When the carpark is full and keeps a waiting queue, the vehicle joins the queue and slot 0 is returned
+func (carpark *Carpark) insertCar(vehicle Vehicle) (int, int, error) {
+	carpark.mu.Lock()
+	defer carpark.mu.Unlock()
+
 	if err := carpark.initStatus(); err != nil {
-		return 0, err
+		return 0, 0, err
 	}
 	if vehicle == nil {
-		return 0, errors.New("Unknown or nil vehicle")
+		return 0, 0, errUnknownVehicle
+	}
+	registration, err := carpark.plate(*vehicle.getRegistration())
+	if err != nil {
+		return 0, 0, err
+	}
+	*vehicle.getRegistration() = registration
+	if _, ok := carpark.registrations[compact(registration)]; ok || carpark.queued(*vehicle.getRegistration()) {
+		return 0, 0, errDuplicate
 	}
 
-	var slotNo int
-	slotsNeeded := vehicle.getSlotsNeeded()
-	var emptySlot = sortedlist.FindSeq(carpark.emptySlots, slotsNeeded) //Get nearest empty slot which was previously occupied
-	if emptySlot != nil {                                               //Park vehicle at nearest empty slot
-		sortedlist.Remove(carpark.emptySlots, emptySlot, slotsNeeded)
-		slotNo = emptySlot.Value.(int)
-	} else { //Park vehicle at next available highest slot
-		if carpark.highestSlot+slotsNeeded > carpark.maxSlot {
-			return 0, errors.New("Sorry, parking lot is full")
+	arrival := carpark.now()
+	chosen, err := carpark.park(vehicle, arrival)
+	if err == errFull && carpark.queue != nil {
+		if err := carpark.enqueue(vehicle, arrival); err != nil {
+			return 0, 0, err
 		}
-		slotNo = carpark.highestSlot + 1
-		carpark.highestSlot += slotsNeeded
+		return 0, 0, nil
+	}
+	if err != nil {
+		return 0, 0, err
 	}
+	return chosen.slot, chosen.floor.level, nil
+}
 
-	//Insert the vehicle into the map
-	*vehicle.getSlot() = slotNo
-	carpark.Map[slotNo] = vehicle
-	return slotNo, nil
+//Remove vehicle from carpark and return the charges for its stay
+func (carpark *Carpark) removeCar(slotNo int) (*receipt, error) {
+	carpark.mu.Lock()
+	defer carpark.mu.Unlock()
+
+	if err := carpark.initStatus(); err != nil {
+		return nil, err
+	}
+	return carpark.leave(slotNo)
 }
 
-//Remove vehicle from carpark
-func (carpark *Carpark) removeCar(slotNo int) error {
+//Remove the vehicle with the given registration number from carpark, freeing every slot it occupies
+func (carpark *Carpark) removeCarWithRegistration(registration string) (*receipt, error) {
+	carpark.mu.Lock()
+	defer carpark.mu.Unlock()
+
 	if err := carpark.initStatus(); err != nil {
-		return err
+		return nil, err
 	}
-	if vehicle, ok := carpark.Map[slotNo]; ok {
-		//Remove vehicle from carpark Map
-		delete(carpark.Map, slotNo)
-		//Add empty slot to the heap
-		sortedlist.Insert(carpark.emptySlots, carpark.emptySlots.Back(), slotNo, vehicle.getSlotsNeeded())
-		return nil
+	vehicle, err := carpark.find(registration)
+	if err != nil {
+		return nil, err
 	}
-	return errors.New("Vehicle non-existent in carpark")
+	return carpark.leave(*vehicle.getSlot())
 }
 
-//Given a vehicle colour, retrieve the vehicle slot and registration numbers
+//Given a vehicle colour in any case or alias, retrieve the vehicle slot and registration numbers
 func (carpark *Carpark) getCarsWithColour(colour string) ([]int, []string, error) {
+	carpark.mu.Lock()
+	defer carpark.mu.Unlock()
+
 	var slots []int
 	var registrations []string
-	for i := 1; i <= carpark.highestSlot; i++ {
-		vehicle, ok := carpark.Map[i]
-		if ok && *vehicle.getColour() == colour {
-			slots = append(slots, *vehicle.getSlot())
-			registrations = append(registrations, *vehicle.getRegistration())
-		}
+	for _, slotNo := range carpark.colours[normalizeColour(colour)] {
+		slots = append(slots, slotNo)
+		registrations = append(registrations, *carpark.Map[slotNo].getRegistration())
 	}
 	if slots == nil {
-		return nil, nil, errors.New("Not found")
+		return nil, nil, errNotFound
 	}
 	return slots, registrations, nil
 }
 
 //Given a vehicle registration number, retrieve the vehicle slot number
 func (carpark *Carpark) getCarWithRegistrationNo(registration string) (int, error) {
-	for _, vehicle := range carpark.Map {
-		if *vehicle.getRegistration() == registration {
-			return *vehicle.getSlot(), nil
-		}
+	carpark.mu.Lock()
+	defer carpark.mu.Unlock()
+
+	vehicle, err := carpark.find(registration)
+	if err != nil {
+		return 0, err
+	}
+	return *vehicle.getSlot(), nil
+}
+
+//Given a vehicle registration number, retrieve the parked vehicle
+func (carpark *Carpark) getVehicle(registration string) (Vehicle, error) {
+	carpark.mu.Lock()
+	defer carpark.mu.Unlock()
+
+	return carpark.find(registration)
+}
+
+//Given a vehicle registration number, compute the charges for the vehicle if it left now
+func (carpark *Carpark) quote(registration string) (*receipt, error) {
+	carpark.mu.Lock()
+	defer carpark.mu.Unlock()
+
+	vehicle, err := carpark.find(registration)
+	if err != nil {
+		return nil, err
 	}
-	return 0, errors.New("Not found")
+	return carpark.charge(vehicle), nil
 }
 
-//Retrieve ordered sequence of vehicles parked in the carpark
+//Retrieve ordered sequence of vehicles parked in the carpark, floor by floor
 func (carpark *Carpark) getStatus() []Vehicle {
+	carpark.mu.Lock()
+	defer carpark.mu.Unlock()
+
+	return carpark.vehicles()
+}
+
+//Retrieve the floor number of a slot, or 0 if the slot does not exist
+func (carpark *Carpark) getLevel(slotNo int) int {
+	carpark.mu.Lock()
+	defer carpark.mu.Unlock()
+
+	if floor := carpark.floorOf(slotNo); floor != nil {
+		return floor.level
+	}
+	return 0
+}
+
+//Check whether the carpark has more than one floor
+func (carpark *Carpark) multiLevel() bool {
+	carpark.mu.Lock()
+	defer carpark.mu.Unlock()
+
+	return len(carpark.floors) > 1
+}
+
+//Check whether the carpark is ready for operation
+func (carpark *Carpark) checkInit() error {
+	carpark.mu.Lock()
+	defer carpark.mu.Unlock()
+
+	return carpark.initStatus()
+}
+
+//The following helpers expect the caller to hold the carpark lock
+
+//Setup the carpark map, its allocation strategy, and each floor with its own range of slots
+func (carpark *Carpark) build(layout []int, strategy allocator) {
+	carpark.Map = make(map[int]Vehicle)
+	carpark.registrations = make(map[string]int)
+	carpark.colours = make(map[string][]int)
+	carpark.floors = nil
+	carpark.strategy = strategy
+	firstSlot := 1
+	for ii, slots := range layout {
+		carpark.floors = append(carpark.floors, newFloor(ii+1, firstSlot, slots))
+		firstSlot += slots
+	}
+}
+
+//Insert a vehicle into the map at slots already taken from the free slots
+func (carpark *Carpark) place(vehicle Vehicle, slotNo int, arrival time.Time) {
+	*vehicle.getSlot() = slotNo
+	*vehicle.getArrival() = arrival
+	carpark.Map[slotNo] = vehicle
+	carpark.registrations[compact(*vehicle.getRegistration())] = slotNo
+	carpark.indexColour(vehicle)
+}
+
+//Park a vehicle in the slots held for it, or let the allocation strategy choose among the free positions
+//in the preferred zones which are not held for other vehicles, filling the lowest floor first
+func (carpark *Carpark) park(vehicle Vehicle, arrival time.Time) (*candidate, error) {
+	carpark.expire(arrival)
+	slotsNeeded := vehicle.getSlotsNeeded()
+	candidates := carpark.claim(vehicle)
+	if candidates == nil {
+		candidates = carpark.positions(vehicle, func(slotNo int, slots int) bool {
+			return carpark.held(slotNo, slots, arrival)
+		})
+	}
+	if len(candidates) == 0 {
+		return nil, errFull
+	}
+	chosen := carpark.allocator().choose(candidates)
+	chosen.floor.occupy(chosen.slot, slotsNeeded)
+
+	err := carpark.record(&event{Type: eventPark, Time: arrival, Vehicle: strings.ToLower(vehicle.getType()),
+		Registration: *vehicle.getRegistration(), Colour: *vehicle.getColour(), Permits: vehicle.getPermits().names(permitNames), Slot: chosen.slot})
+	if err != nil {
+		chosen.floor.release(chosen.slot, slotsNeeded)
+		return nil, err
+	}
+	carpark.place(vehicle, chosen.slot, arrival)
+	carpark.arrived(*vehicle.getRegistration())
+	carpark.changed()
+	return &chosen, nil
+}
+
+//Forget the reservation and the place in the waiting queue of a vehicle which parked
+func (carpark *Carpark) arrived(registration string) {
+	delete(carpark.reservations, compact(registration))
+	if carpark.queue != nil {
+		carpark.queue.remove(registration)
+	}
+}
+
+//Record a vehicle leaving its slot, admit waiting vehicles into the freed slots,
+//and return the charges for its stay
+func (carpark *Carpark) leave(slotNo int) (*receipt, error) {
+	if _, ok := carpark.Map[slotNo]; !ok {
+		return nil, errVehicleNotFound
+	}
+	if err := carpark.record(&event{Type: eventLeave, Time: carpark.now(), Slot: slotNo}); err != nil {
+		return nil, err
+	}
+	vehicle, err := carpark.remove(slotNo)
+	if err != nil {
+		return nil, err
+	}
+	carpark.changed()
+	receipt := carpark.charge(vehicle)
+	receipt.admitted = carpark.admit()
+	return receipt, nil
+}
+
+//Remove a vehicle from the map and return its slots to the floor
+func (carpark *Carpark) remove(slotNo int) (Vehicle, error) {
+	vehicle, ok := carpark.Map[slotNo]
+	if !ok {
+		return nil, errVehicleNotFound
+	}
+	delete(carpark.Map, slotNo)
+	delete(carpark.registrations, compact(*vehicle.getRegistration()))
+	carpark.unindexColour(vehicle)
+	carpark.floorOf(slotNo).release(slotNo, vehicle.getSlotsNeeded())
+	return vehicle, nil
+}
+
+//Retrieve ordered sequence of vehicles parked in the carpark, floor by floor
+func (carpark *Carpark) vehicles() []Vehicle {
 	var vehicles []Vehicle
-	for i := 1; i <= carpark.highestSlot; i++ {
-		vehicle, ok := carpark.Map[i]
-		if ok {
-			vehicles = append(vehicles, vehicle)
+	for _, floor := range carpark.floors {
+		for i := floor.firstSlot; i <= floor.highestSlot; i++ {
+			vehicle, ok := carpark.Map[i]
+			if ok {
+				vehicles = append(vehicles, vehicle)
+			}
 		}
 	}
 	return vehicles
 }
 
+//Find a parked vehicle by its registration number
+func (carpark *Carpark) find(registration string) (Vehicle, error) {
+	registration, err := carpark.plate(registration)
+	if err != nil {
+		return nil, err
+	}
+	if slotNo, ok := carpark.registrations[compact(registration)]; ok {
+		return carpark.Map[slotNo], nil
+	}
+	return nil, errNotFound
+}
+
+//Rebuild the indexes of the parked vehicles from the carpark map
+func (carpark *Carpark) reindex() {
+	if carpark.Map == nil {
+		return
+	}
+	carpark.registrations = make(map[string]int)
+	carpark.colours = make(map[string][]int)
+	for slotNo, vehicle := range carpark.Map {
+		carpark.registrations[compact(*vehicle.getRegistration())] = slotNo
+		carpark.indexColour(vehicle)
+	}
+}
+
+//Add a parked vehicle to the colour index, keeping the slots in ascending order
+func (carpark *Carpark) indexColour(vehicle Vehicle) {
+	colour := normalizeColour(*vehicle.getColour())
+	slots := carpark.colours[colour]
+	ii := sort.SearchInts(slots, *vehicle.getSlot())
+	slots = append(slots, 0)
+	copy(slots[ii+1:], slots[ii:])
+	slots[ii] = *vehicle.getSlot()
+	carpark.colours[colour] = slots
+}
+
+//Remove a parked vehicle from the colour index
+func (carpark *Carpark) unindexColour(vehicle Vehicle) {
+	colour := normalizeColour(*vehicle.getColour())
+	slots := carpark.colours[colour]
+	ii := sort.SearchInts(slots, *vehicle.getSlot())
+	if ii == len(slots) || slots[ii] != *vehicle.getSlot() {
+		return
+	}
+	if slots = append(slots[:ii], slots[ii+1:]...); len(slots) == 0 {
+		delete(carpark.colours, colour)
+		return
+	}
+	carpark.colours[colour] = slots
+}
+
+//Compute the charges for a vehicle parked until now
+func (carpark *Carpark) charge(vehicle Vehicle) *receipt {
+	tariffs := carpark.tariffs
+	if tariffs == nil {
+		tariffs = defaultTariffs
+	}
+	receipt := newReceipt(vehicle, carpark.now())
+	receipt.fee = tariffs.fee(vehicle.getTariff(), receipt.arrival, receipt.departure)
+	return receipt
+}
+
+//Retrieve the allocation strategy of the carpark
+func (carpark *Carpark) allocator() allocator {
+	if carpark.strategy == nil {
+		return firstFit{}
+	}
+	return carpark.strategy
+}
+
+//Retrieve the types of vehicle the carpark parks
+func (carpark *Carpark) types() vehicleRegistry {
+	if carpark.vehicleTypes == nil {
+		return defaultVehicleTypes
+	}
+	return carpark.vehicleTypes
+}
+
+//Retrieve the current time from the carpark clock
+func (carpark *Carpark) now() time.Time {
+	if carpark.clock == nil {
+		return time.Now()
+	}
+	return carpark.clock()
+}
+
+//Find the floor holding a slot
+func (carpark *Carpark) floorOf(slotNo int) *floor {
+	for _, floor := range carpark.floors {
+		if floor.contains(slotNo) {
+			return floor
+		}
+	}
+	return nil
+}
+
 //Check whether the carpark has been initialized
 func (carpark *Carpark) initStatus() error {
 	if carpark.Map == nil {
-		return errors.New("Carpark not initialized")
+		return errNotInitialized
 	}
 	return nil
 }
@@ -2,19 +2,32 @@ package main
 
 import (
 	"container/list"
+	"fmt"
 	"reflect"
+	"sync"
 	"testing"
+	"time"
 )
 
+//testTime is the time reported by the carpark clock in tests
+var testTime = time.Date(2018, 11, 1, 9, 0, 0, 0, time.UTC)
+
+//fixedClock returns a carpark clock which always reports the given time
+func fixedClock(now time.Time) func() time.Time {
+	return func() time.Time { return now }
+}
+
 //variables act as a struct of all parameters used in testing
 type variables struct {
-	vehicle0     *Motorcycle
-	vehicle1     *Motorcycle
-	vehicle2     *Motorcycle
+	vehicle0     *baseVehicle
+	vehicle1     *baseVehicle
+	vehicle2     *baseVehicle
+	vehicle3     *baseVehicle
 	map0         map[int]Vehicle
 	map1         map[int]Vehicle
 	map2         map[int]Vehicle
 	mapAll       map[int]Vehicle
+	mapUpper     map[int]Vehicle
 	item1        interface{}
 	item2        interface{}
 	emptySlot0   *list.List
@@ -26,9 +39,10 @@ type variables struct {
 //values() acts a storage of default values and return a 'variables' struct containing default values
 func values() variables {
 	defaultValues := variables{
-		vehicle0:   &Motorcycle{baseVehicle: baseVehicle{registration: "KA-01-HH-2701", colour: "Blue"}},
-		vehicle1:   &Motorcycle{baseVehicle: baseVehicle{registration: "KA-01-HH-1234", colour: "White", slot: 1}},
-		vehicle2:   &Motorcycle{baseVehicle: baseVehicle{registration: "KA-01-HH-7777", colour: "Red", slot: 2}},
+		vehicle0:   &baseVehicle{name: "Motorcycle", slots: 1, tariff: "Motorcycle", registration: "KA-01-HH-2701", colour: "Blue", arrival: testTime},
+		vehicle1:   &baseVehicle{name: "Motorcycle", slots: 1, tariff: "Motorcycle", registration: "KA-01-HH-1234", colour: "White", slot: 1, arrival: testTime},
+		vehicle2:   &baseVehicle{name: "Motorcycle", slots: 1, tariff: "Motorcycle", registration: "KA-01-HH-7777", colour: "Red", slot: 2, arrival: testTime},
+		vehicle3:   &baseVehicle{name: "Motorcycle", slots: 1, tariff: "Motorcycle", registration: "KA-01-HH-2701", colour: "Blue", slot: 3, arrival: testTime},
 		map0:       make(map[int]Vehicle),
 		item1:      1,
 		item2:      2,
@@ -37,6 +51,7 @@ func values() variables {
 	defaultValues.map1 = map[int]Vehicle{1: defaultValues.vehicle1}
 	defaultValues.map2 = map[int]Vehicle{2: defaultValues.vehicle2}
 	defaultValues.mapAll = map[int]Vehicle{1: defaultValues.vehicle1, 2: defaultValues.vehicle2}
+	defaultValues.mapUpper = map[int]Vehicle{1: defaultValues.vehicle1, 2: defaultValues.vehicle2, 3: defaultValues.vehicle3}
 	defaultValues.emptySlot1 = list.New()
 	defaultValues.emptySlot1.PushBack(defaultValues.item1)
 	defaultValues.emptySlot2 = list.New()
@@ -48,19 +63,42 @@ func values() variables {
 	return defaultValues
 }
 
+//singleFloor builds the floors of a single-level carpark
+func singleFloor(emptySlots *list.List, highestSlot int, maxSlot int) []*floor {
+	return []*floor{{level: 1, firstSlot: 1, highestSlot: highestSlot, maxSlot: maxSlot, emptySlots: emptySlots}}
+}
+
+//twoFloors builds the floors of a two-level carpark with 'slots' slots on each floor
+func twoFloors(highestSlot1 int, highestSlot2 int, slots int) []*floor {
+	return []*floor{
+		{level: 1, firstSlot: 1, highestSlot: highestSlot1, maxSlot: slots, emptySlots: list.New()},
+		{level: 2, firstSlot: slots + 1, highestSlot: highestSlot2, maxSlot: 2 * slots, emptySlots: list.New()},
+	}
+}
+
+//withIndexes builds the indexes of a carpark from its map
+func withIndexes(carpark *Carpark) *Carpark {
+	carpark.reindex()
+	return carpark
+}
+
 //Compare two 'Carpark' structs
 func compareCarpark(t *testing.T, carpark *Carpark, wantCarpark *Carpark) {
 	if !reflect.DeepEqual(carpark.Map, wantCarpark.Map) ||
-		!reflect.DeepEqual(carpark.emptySlots, wantCarpark.emptySlots) ||
-		carpark.highestSlot != wantCarpark.highestSlot ||
-		carpark.maxSlot != wantCarpark.maxSlot {
+		!reflect.DeepEqual(carpark.registrations, wantCarpark.registrations) ||
+		!reflect.DeepEqual(carpark.colours, wantCarpark.colours) ||
+		!reflect.DeepEqual(carpark.floors, wantCarpark.floors) ||
+		!reflect.DeepEqual(carpark.zones, wantCarpark.zones) ||
+		!reflect.DeepEqual(carpark.overflow, wantCarpark.overflow) ||
+		!reflect.DeepEqual(carpark.attributes, wantCarpark.attributes) ||
+		!reflect.DeepEqual(carpark.reservations, wantCarpark.reservations) {
 		t.Errorf("gotCarpark = %v, wantCarpark = %v", carpark, wantCarpark)
 	}
 }
 
 func TestCarpark_init(t *testing.T) {
 	type args struct {
-		maxSlot int
+		layout []int
 	}
 	tests := []struct {
 		name        string
@@ -71,20 +109,32 @@ func TestCarpark_init(t *testing.T) {
 	}{
 		{name: "Carpark not initialized",
 			carpark:     &Carpark{},
-			args:        args{maxSlot: 12},
+			args:        args{layout: []int{12}},
 			wantErr:     false,
-			wantCarpark: &Carpark{Map: values().map0, emptySlots: values().emptySlot0, highestSlot: 0, maxSlot: 12},
+			wantCarpark: withIndexes(&Carpark{Map: values().map0, floors: singleFloor(values().emptySlot0, 0, 12)}),
+		},
+		{name: "Multi-level carpark",
+			carpark:     &Carpark{},
+			args:        args{layout: []int{4, 4}},
+			wantErr:     false,
+			wantCarpark: withIndexes(&Carpark{Map: values().map0, floors: twoFloors(0, 4, 4)}),
+		},
+		{name: "Floor without slots",
+			carpark:     &Carpark{},
+			args:        args{layout: []int{4, 0}},
+			wantErr:     true,
+			wantCarpark: &Carpark{},
 		},
 		{name: "Carpark already initialized",
-			carpark:     &Carpark{Map: values().map0, emptySlots: values().emptySlot0, highestSlot: 8, maxSlot: 10},
-			args:        args{maxSlot: 12},
+			carpark:     withIndexes(&Carpark{Map: values().map0, floors: singleFloor(values().emptySlot0, 8, 10)}),
+			args:        args{layout: []int{12}},
 			wantErr:     true,
-			wantCarpark: &Carpark{Map: values().map0, emptySlots: values().emptySlot0, highestSlot: 8, maxSlot: 10},
+			wantCarpark: withIndexes(&Carpark{Map: values().map0, floors: singleFloor(values().emptySlot0, 8, 10)}),
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			err := tt.carpark.init(tt.args.maxSlot)
+			err := tt.carpark.init(nil, tt.args.layout...)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("Carpark.init() error = %v, wantErr = %v", err, tt.wantErr)
 				return
@@ -96,13 +146,14 @@ func TestCarpark_init(t *testing.T) {
 
 func TestCarpark_insertCar(t *testing.T) {
 	type args struct {
-		car *Motorcycle
+		car *baseVehicle
 	}
 	tests := []struct {
 		name        string
 		carpark     *Carpark
 		args        args
 		want        int
+		wantLevel   int
 		wantErr     bool
 		wantCarpark *Carpark
 	}{
@@ -114,31 +165,41 @@ func TestCarpark_insertCar(t *testing.T) {
 			wantCarpark: &Carpark{},
 		},
 		{name: "Insert car into new slot",
-			carpark:     &Carpark{Map: values().map1, emptySlots: values().emptySlot0, highestSlot: 1, maxSlot: 10},
+			carpark:     withIndexes(&Carpark{clock: fixedClock(testTime), Map: values().map1, floors: singleFloor(values().emptySlot0, 1, 10)}),
 			args:        args{car: values().vehicle2},
 			want:        2,
+			wantLevel:   1,
 			wantErr:     false,
-			wantCarpark: &Carpark{Map: values().mapAll, emptySlots: values().emptySlot0, highestSlot: 2, maxSlot: 10},
+			wantCarpark: withIndexes(&Carpark{Map: values().mapAll, floors: singleFloor(values().emptySlot0, 2, 10)}),
 		},
 		{name: "Insert car into a previously occupied but now free slot",
-			carpark:     &Carpark{Map: values().map2, emptySlots: values().emptySlot1, highestSlot: 2, maxSlot: 10},
+			carpark:     withIndexes(&Carpark{clock: fixedClock(testTime), Map: values().map2, floors: singleFloor(values().emptySlot1, 2, 10)}),
 			args:        args{car: values().vehicle1},
 			want:        1,
+			wantLevel:   1,
 			wantErr:     false,
-			wantCarpark: &Carpark{Map: values().mapAll, emptySlots: values().emptySlot0, highestSlot: 2, maxSlot: 10},
+			wantCarpark: withIndexes(&Carpark{Map: values().mapAll, floors: singleFloor(values().emptySlot0, 2, 10)}),
 		},
 		{name: "Insert car beyond maxSlot",
-			carpark:     &Carpark{Map: values().mapAll, emptySlots: values().emptySlot0, highestSlot: 2, maxSlot: 2},
+			carpark:     withIndexes(&Carpark{clock: fixedClock(testTime), Map: values().mapAll, floors: singleFloor(values().emptySlot0, 2, 2)}),
 			args:        args{car: values().vehicle0},
 			want:        0,
 			wantErr:     true,
-			wantCarpark: &Carpark{Map: values().mapAll, emptySlots: values().emptySlot0, highestSlot: 2, maxSlot: 2},
+			wantCarpark: withIndexes(&Carpark{Map: values().mapAll, floors: singleFloor(values().emptySlot0, 2, 2)}),
+		},
+		{name: "Insert car on upper floor when lower floor is full",
+			carpark:     withIndexes(&Carpark{clock: fixedClock(testTime), Map: values().mapAll, floors: twoFloors(2, 2, 2)}),
+			args:        args{car: values().vehicle0},
+			want:        3,
+			wantLevel:   2,
+			wantErr:     false,
+			wantCarpark: withIndexes(&Carpark{Map: values().mapUpper, floors: twoFloors(2, 3, 2)}),
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := tt.carpark.insertCar(tt.args.car)
+			got, gotLevel, err := tt.carpark.insertCar(tt.args.car)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("Carpark.insertCar() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -146,6 +207,9 @@ func TestCarpark_insertCar(t *testing.T) {
 			if got != tt.want {
 				t.Errorf("Carpark.insertCar() = %v, want %v", got, tt.want)
 			}
+			if gotLevel != tt.wantLevel {
+				t.Errorf("Carpark.insertCar() level = %v, want %v", gotLevel, tt.wantLevel)
+			}
 			compareCarpark(t, tt.carpark, tt.wantCarpark)
 		})
 	}
@@ -159,6 +223,7 @@ func TestCarpark_removeCar(t *testing.T) {
 		name        string
 		carpark     *Carpark
 		args        args
+		wantFee     int
 		wantErr     bool
 		wantCarpark *Carpark
 	}{
@@ -169,24 +234,29 @@ func TestCarpark_removeCar(t *testing.T) {
 			wantCarpark: &Carpark{},
 		},
 		{name: "Remove car",
-			carpark:     &Carpark{Map: values().mapAll, emptySlots: values().emptySlot0, highestSlot: 2, maxSlot: 10},
+			carpark:     withIndexes(&Carpark{clock: fixedClock(testTime.Add(90 * time.Minute)), Map: values().mapAll, floors: singleFloor(values().emptySlot0, 2, 10)}),
 			args:        args{slotNo: 1},
+			wantFee:     150,
 			wantErr:     false,
-			wantCarpark: &Carpark{Map: values().map2, emptySlots: values().emptySlot1, highestSlot: 2, maxSlot: 10},
+			wantCarpark: withIndexes(&Carpark{Map: values().map2, floors: singleFloor(values().emptySlot1, 2, 10)}),
 		},
 		{name: "Remove non-existent car",
-			carpark:     &Carpark{Map: values().map1, emptySlots: values().emptySlot2, highestSlot: 2, maxSlot: 10},
+			carpark:     withIndexes(&Carpark{Map: values().map1, floors: singleFloor(values().emptySlot2, 2, 10)}),
 			args:        args{slotNo: 2},
 			wantErr:     true,
-			wantCarpark: &Carpark{Map: values().map1, emptySlots: values().emptySlot2, highestSlot: 2, maxSlot: 10},
+			wantCarpark: withIndexes(&Carpark{Map: values().map1, floors: singleFloor(values().emptySlot2, 2, 10)}),
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if err := tt.carpark.removeCar(tt.args.slotNo); (err != nil) != tt.wantErr {
+			got, err := tt.carpark.removeCar(tt.args.slotNo)
+			if (err != nil) != tt.wantErr {
 				t.Errorf("Carpark.removeCar() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
+			if got != nil && got.fee != tt.wantFee {
+				t.Errorf("Carpark.removeCar() fee = %v, want %v", got.fee, tt.wantFee)
+			}
 			compareCarpark(t, tt.carpark, tt.wantCarpark)
 		})
 	}
@@ -205,21 +275,38 @@ func TestCarpark_getCarsWithColour(t *testing.T) {
 		wantErr bool
 	}{
 		{name: "Carpark with car of requested colour",
-			carpark: &Carpark{Map: values().map1, emptySlots: values().emptySlot0, highestSlot: 1, maxSlot: 10},
+			carpark: withIndexes(&Carpark{Map: values().map1, floors: singleFloor(values().emptySlot0, 1, 10)}),
 			args:    args{colour: "White"},
 			want:    []int{1},
 			want1:   []string{"KA-01-HH-1234"},
 			wantErr: false,
 		},
+		{name: "Colour in a different case",
+			carpark: withIndexes(&Carpark{Map: values().mapAll, floors: singleFloor(values().emptySlot0, 2, 10)}),
+			args:    args{colour: " white "},
+			want:    []int{1},
+			want1:   []string{"KA-01-HH-1234"},
+			wantErr: false,
+		},
+		{name: "Colour alias",
+			carpark: withIndexes(&Carpark{Map: map[int]Vehicle{
+				1: &baseVehicle{name: "Motorcycle", slots: 1, tariff: "Motorcycle", registration: "KA-01-HH-1234", colour: "Gray", slot: 1},
+				2: &baseVehicle{name: "Motorcycle", slots: 1, tariff: "Motorcycle", registration: "KA-01-HH-7777", colour: "grey", slot: 2},
+			}, floors: singleFloor(values().emptySlot0, 2, 10)}),
+			args:    args{colour: "GREY"},
+			want:    []int{1, 2},
+			want1:   []string{"KA-01-HH-1234", "KA-01-HH-7777"},
+			wantErr: false,
+		},
 		{name: "Carpark without car of requested colour",
-			carpark: &Carpark{Map: values().map2, emptySlots: values().emptySlot1, highestSlot: 2, maxSlot: 10},
+			carpark: withIndexes(&Carpark{Map: values().map2, floors: singleFloor(values().emptySlot1, 2, 10)}),
 			args:    args{colour: "White"},
 			want:    nil,
 			want1:   nil,
 			wantErr: true,
 		},
 		{name: "Empty carpark",
-			carpark: &Carpark{Map: values().map0, emptySlots: values().emptySlot0, highestSlot: 0, maxSlot: 10},
+			carpark: withIndexes(&Carpark{Map: values().map0, floors: singleFloor(values().emptySlot0, 0, 10)}),
 			args:    args{colour: "White"},
 			want:    nil,
 			want1:   nil,
@@ -262,19 +349,19 @@ func TestCarpark_getCarWithRegistrationNo(t *testing.T) {
 		wantErr bool
 	}{
 		{name: "Carpark with car of requested colour",
-			carpark: &Carpark{Map: values().map1, emptySlots: values().emptySlot0, highestSlot: 1, maxSlot: 10},
+			carpark: withIndexes(&Carpark{Map: values().map1, floors: singleFloor(values().emptySlot0, 1, 10)}),
 			args:    args{registration: "KA-01-HH-1234"},
 			want:    1,
 			wantErr: false,
 		},
 		{name: "Carpark without car of requested colour",
-			carpark: &Carpark{Map: values().map2, emptySlots: values().emptySlot1, highestSlot: 2, maxSlot: 10},
+			carpark: withIndexes(&Carpark{Map: values().map2, floors: singleFloor(values().emptySlot1, 2, 10)}),
 			args:    args{registration: "KA-01-HH-1234"},
 			want:    0,
 			wantErr: true,
 		},
 		{name: "Empty carpark",
-			carpark: &Carpark{Map: values().map0, emptySlots: values().emptySlot0, highestSlot: 0, maxSlot: 10},
+			carpark: withIndexes(&Carpark{Map: values().map0, floors: singleFloor(values().emptySlot0, 0, 10)}),
 			args:    args{registration: "KA-01-HH-1234"},
 			want:    0,
 			wantErr: true,
@@ -311,13 +398,17 @@ func TestCarpark_getStatus(t *testing.T) {
 			want:    nil,
 		},
 		{name: "Empty carpark",
-			carpark: &Carpark{Map: values().map0, emptySlots: values().emptySlot0, highestSlot: 0, maxSlot: 10},
+			carpark: withIndexes(&Carpark{Map: values().map0, floors: singleFloor(values().emptySlot0, 0, 10)}),
 			want:    nil,
 		},
 		{name: "Carpark with cars",
-			carpark: &Carpark{Map: values().mapAll, emptySlots: values().emptySlot0, highestSlot: 2, maxSlot: 10},
+			carpark: withIndexes(&Carpark{Map: values().mapAll, floors: singleFloor(values().emptySlot0, 2, 10)}),
 			want:    []Vehicle{values().vehicle1, values().vehicle2},
 		},
+		{name: "Multi-level carpark with cars",
+			carpark: withIndexes(&Carpark{Map: values().mapUpper, floors: twoFloors(2, 3, 2)}),
+			want:    []Vehicle{values().vehicle1, values().vehicle2, values().vehicle3},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -327,3 +418,170 @@ func TestCarpark_getStatus(t *testing.T) {
 		})
 	}
 }
+
+func TestCarpark_quote(t *testing.T) {
+	type args struct {
+		registration string
+	}
+	tests := []struct {
+		name    string
+		carpark *Carpark
+		args    args
+		wantFee int
+		wantErr bool
+	}{
+		{name: "Quote parked vehicle",
+			carpark: withIndexes(&Carpark{clock: fixedClock(testTime.Add(150 * time.Minute)), Map: values().mapAll, floors: singleFloor(values().emptySlot0, 2, 10)}),
+			args:    args{registration: "KA-01-HH-7777"},
+			wantFee: 200,
+			wantErr: false,
+		},
+		{name: "Quote vehicle not in carpark",
+			carpark: withIndexes(&Carpark{Map: values().map1, floors: singleFloor(values().emptySlot0, 1, 10)}),
+			args:    args{registration: "KA-01-HH-7777"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.carpark.quote(tt.args.registration)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Carpark.quote() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != nil && got.fee != tt.wantFee {
+				t.Errorf("Carpark.quote() fee = %v, want %v", got.fee, tt.wantFee)
+			}
+			if len(tt.carpark.Map) != len(values().mapAll) && !tt.wantErr {
+				t.Errorf("Carpark.quote() removed a vehicle from the carpark")
+			}
+		})
+	}
+}
+
+func TestCarpark_concurrentGates(t *testing.T) {
+	carpark := &Carpark{}
+	if err := carpark.init(nil, 20, 20); err != nil {
+		t.Fatal(err)
+	}
+
+	//Track the slots handed out so that no slot is ever given to two vehicles at once
+	var mu sync.Mutex
+	occupied := make(map[int]string)
+	claim := func(vehicle Vehicle) {
+		mu.Lock()
+		defer mu.Unlock()
+		for slotNo := *vehicle.getSlot(); slotNo < *vehicle.getSlot()+vehicle.getSlotsNeeded(); slotNo++ {
+			if owner, ok := occupied[slotNo]; ok {
+				t.Errorf("Slot %v given to %v while occupied by %v", slotNo, *vehicle.getRegistration(), owner)
+			}
+			occupied[slotNo] = *vehicle.getRegistration()
+		}
+	}
+	unclaim := func(vehicle Vehicle) {
+		mu.Lock()
+		defer mu.Unlock()
+		for slotNo := *vehicle.getSlot(); slotNo < *vehicle.getSlot()+vehicle.getSlotsNeeded(); slotNo++ {
+			delete(occupied, slotNo)
+		}
+	}
+
+	//Each gate repeatedly parks a vehicle, queries the carpark, and lets the vehicle leave
+	types := []string{"motorcycle", "car", "bus"}
+	var wg sync.WaitGroup
+	for gate := 0; gate < 16; gate++ {
+		wg.Add(1)
+		go func(gate int) {
+			defer wg.Done()
+			for ii := 0; ii < 200; ii++ {
+				registration := fmt.Sprintf("KA-%02d-HH-%04d", gate, ii)
+				vehicle := defaultVehicleTypes.newVehicle(types[(gate+ii)%len(types)], registration, "White")
+				slotNo, _, err := carpark.insertCar(vehicle)
+				if err == errFull {
+					continue
+				}
+				if err != nil {
+					t.Errorf("Carpark.insertCar() error = %v", err)
+					return
+				}
+				claim(vehicle)
+				carpark.getCarsWithColour("White")
+				carpark.getStatus()
+				if got, err := carpark.getCarWithRegistrationNo(registration); err != nil || got != slotNo {
+					t.Errorf("Carpark.getCarWithRegistrationNo() = %v, %v, want %v", got, err, slotNo)
+				}
+				unclaim(vehicle)
+				if _, err := carpark.removeCar(slotNo); err != nil {
+					t.Errorf("Carpark.removeCar() error = %v", err)
+				}
+			}
+		}(gate)
+	}
+	wg.Wait()
+
+	if got := carpark.getStatus(); got != nil {
+		t.Errorf("Carpark.getStatus() = %v after every vehicle left, want none", got)
+	}
+}
+
+func TestCarpark_insertDuplicate(t *testing.T) {
+	carpark := withIndexes(&Carpark{clock: fixedClock(testTime), Map: values().mapAll, floors: singleFloor(values().emptySlot0, 2, 10)})
+	duplicate := &baseVehicle{name: "Motorcycle", slots: 1, tariff: "Motorcycle", registration: "KA-01-HH-1234", colour: "White"}
+	if _, _, err := carpark.insertCar(duplicate); err != errDuplicate {
+		t.Errorf("Carpark.insertCar() error = %v, want %v", err, errDuplicate)
+	}
+	compareCarpark(t, carpark, withIndexes(&Carpark{Map: values().mapAll, floors: singleFloor(values().emptySlot0, 2, 10)}))
+
+	//The registration can be parked again once the vehicle has left
+	carpark.removeCar(1)
+	if slotNo, _, err := carpark.insertCar(duplicate); err != nil || slotNo != 1 {
+		t.Errorf("Carpark.insertCar() = %v, %v, want 1, nil", slotNo, err)
+	}
+}
+
+func TestCarpark_removeCarWithRegistration(t *testing.T) {
+	type args struct {
+		registration string
+	}
+	tests := []struct {
+		name        string
+		carpark     *Carpark
+		args        args
+		wantSlots   []int
+		wantErr     bool
+		wantCarpark *Carpark
+	}{
+		{name: "Carpark not initialized",
+			carpark:     &Carpark{},
+			args:        args{registration: "KA-01-HH-1234"},
+			wantErr:     true,
+			wantCarpark: &Carpark{},
+		},
+		{name: "Remove car",
+			carpark:     withIndexes(&Carpark{Map: values().mapAll, floors: singleFloor(values().emptySlot0, 2, 10)}),
+			args:        args{registration: "KA-01-HH-1234"},
+			wantSlots:   []int{1},
+			wantErr:     false,
+			wantCarpark: withIndexes(&Carpark{Map: values().map2, floors: singleFloor(values().emptySlot1, 2, 10)}),
+		},
+		{name: "Remove non-existent car",
+			carpark:     withIndexes(&Carpark{Map: values().map2, floors: singleFloor(values().emptySlot1, 2, 10)}),
+			args:        args{registration: "KA-01-HH-1234"},
+			wantErr:     true,
+			wantCarpark: withIndexes(&Carpark{Map: values().map2, floors: singleFloor(values().emptySlot1, 2, 10)}),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.carpark.removeCarWithRegistration(tt.args.registration)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Carpark.removeCarWithRegistration() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != nil && !reflect.DeepEqual(got.slots, tt.wantSlots) {
+				t.Errorf("Carpark.removeCarWithRegistration() slots = %v, want %v", got.slots, tt.wantSlots)
+			}
+			compareCarpark(t, tt.carpark, tt.wantCarpark)
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package main
+
+import "strings"
+
+//colourAliases maps every accepted spelling of a colour onto its canonical name
+var colourAliases = map[string]string{
+	"white":  "white",
+	"black":  "black",
+	"grey":   "grey",
+	"gray":   "grey",
+	"silver": "silver",
+	"red":    "red",
+	"maroon": "maroon",
+	"blue":   "blue",
+	"navy":   "navy",
+	"green":  "green",
+	"yellow": "yellow",
+	"orange": "orange",
+	"brown":  "brown",
+	"beige":  "beige",
+	"gold":   "gold",
+	"golden": "gold",
+	"purple": "purple",
+	"violet": "purple",
+	"pink":   "pink",
+}
+
+//normalizeColour converts a colour into its canonical name, ignoring case and surrounding spaces;
+//colours outside the vocabulary are only lower-cased
+func normalizeColour(colour string) string {
+	key := strings.ToLower(strings.Join(strings.Fields(colour), " "))
+	if canonical, ok := colourAliases[key]; ok {
+		return canonical
+	}
+	if words := strings.Fields(key); len(words) > 1 { //Shades such as "dark gray" keep their qualifier
+		if canonical, ok := colourAliases[words[len(words)-1]]; ok {
+			words[len(words)-1] = canonical
+			return strings.Join(words, " ")
+		}
+	}
+	return key
+}
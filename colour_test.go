@@ -0,0 +1,24 @@
+package main
+
+import "testing"
+
+func Test_normalizeColour(t *testing.T) {
+	tests := []struct {
+		name   string
+		colour string
+		want   string
+	}{
+		{name: "Canonical colour", colour: "white", want: "white"},
+		{name: "Mixed case", colour: "WhiTe", want: "white"},
+		{name: "Alias", colour: "Gray", want: "grey"},
+		{name: "Shade of an alias", colour: "Dark  Gray", want: "dark grey"},
+		{name: "Colour outside the vocabulary", colour: "Teal", want: "teal"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := normalizeColour(tt.colour); got != tt.want {
+				t.Errorf("normalizeColour() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
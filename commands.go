@@ -0,0 +1,609 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//Kinds of value a command argument takes
+const (
+	argString = iota //Any token
+	argInt           //Whole number
+	argTime          //Time such as 2006-01-02T15:04
+)
+
+//argument describes an argument of a command
+type argument struct {
+	name     string //Name shown in the usage of the command
+	kind     int    //Kind of value taken
+	optional bool   //Whether the argument may be left out, only when no required argument follows
+	rest     bool   //Whether the argument takes every remaining token, only for the last argument and strings
+}
+
+//arguments holds the values of the arguments given to a command, keyed by name
+type arguments map[string]interface{}
+
+//str returns the value of a string argument, or "" when it was left out
+func (args arguments) str(name string) string {
+	value, _ := args[name].(string)
+	return value
+}
+
+//tokens returns the tokens taken by an argument which takes every remaining token
+func (args arguments) tokens(name string) []string {
+	value, _ := args[name].([]string)
+	return value
+}
+
+//number returns the value of a whole number argument, or 0 when it was left out
+func (args arguments) number(name string) int {
+	value, _ := args[name].(int)
+	return value
+}
+
+//when returns the value of a time argument, or the zero time when it was left out
+func (args arguments) when(name string) time.Time {
+	value, _ := args[name].(time.Time)
+	return value
+}
+
+//has checks whether an argument was given
+func (args arguments) has(name string) bool {
+	_, ok := args[name]
+	return ok
+}
+
+//consoleCommand describes a command of the carpark console
+type consoleCommand struct {
+	name string                                                    //Verb which runs the command, in lower case
+	args []argument                                                //Arguments taken, in order
+	help string                                                    //What the command does, shown by help
+	run  func(carpark *Carpark, args arguments, out *report) error //Carries out the command, adding its result to the report
+}
+
+//usage formats the verb and arguments of a command, with optional arguments in brackets
+func (command *consoleCommand) usage() string {
+	words := []string{command.name}
+	for _, arg := range command.args {
+		word := arg.name
+		if arg.rest {
+			word += "..."
+		}
+		if arg.optional {
+			word = "[" + word + "]"
+		} else {
+			word = "<" + word + ">"
+		}
+		words = append(words, word)
+	}
+	return strings.Join(words, " ")
+}
+
+//parse converts the tokens following the verb into argument values, reporting the first token which does not fit
+func (command *consoleCommand) parse(tokens []string, errorAt func(token int, reason string) error) (arguments, error) {
+	args := make(arguments)
+	ii := 1
+	for _, arg := range command.args {
+		switch {
+		case arg.rest:
+			if len(tokens) == ii && !arg.optional {
+				return nil, errorAt(ii, fmt.Sprintf("missing <%v>, usage: %v", arg.name, command.usage()))
+			}
+			args[arg.name] = tokens[ii:]
+			ii = len(tokens)
+			continue
+		case ii == len(tokens) && arg.optional:
+			continue
+		case ii == len(tokens):
+			return nil, errorAt(ii, fmt.Sprintf("missing <%v>, usage: %v", arg.name, command.usage()))
+		}
+		switch token := tokens[ii]; arg.kind {
+		case argInt:
+			value, err := strconv.Atoi(token)
+			if err != nil {
+				return nil, errorAt(ii, fmt.Sprintf("%q is not a number", token))
+			}
+			args[arg.name] = value
+		case argTime:
+			value, err := parseTime(token)
+			if err != nil {
+				return nil, errorAt(ii, fmt.Sprintf("%q is not a time such as 2006-01-02T15:04", token))
+			}
+			args[arg.name] = value
+		default:
+			args[arg.name] = token
+		}
+		ii++
+	}
+	if ii < len(tokens) {
+		return nil, errorAt(ii, fmt.Sprintf("unexpected argument %q, usage: %v", tokens[ii], command.usage()))
+	}
+	return args, nil
+}
+
+//errExit is returned by the exit command to end the operation of the carpark
+var errExit = errors.New("Exit")
+
+//commandRegistry holds the commands of the carpark console. Only this package registers commands:
+//package main cannot be imported, so other programs cannot add their own until the registry moves into a package of its own
+type commandRegistry struct {
+	commands map[string]*consoleCommand //Commands keyed by verb
+	names    []string                   //Verbs in the order the commands were registered
+}
+
+//consoleCommands holds the commands run by operateCarpark
+var consoleCommands = &commandRegistry{commands: make(map[string]*consoleCommand)}
+
+//register adds a command to the registry, refusing a verb already taken or arguments in an invalid order
+func (registry *commandRegistry) register(command consoleCommand) error {
+	command.name = strings.ToLower(command.name)
+	switch {
+	case command.name == "" || strings.ContainsAny(command.name, " \t#\"'\\"):
+		return fmt.Errorf("Invalid command name %q", command.name)
+	case registry.commands[command.name] != nil:
+		return fmt.Errorf("Command %v is already registered", command.name)
+	case command.run == nil:
+		return fmt.Errorf("Command %v has nothing to run", command.name)
+	}
+	for ii, arg := range command.args {
+		switch last := ii == len(command.args)-1; {
+		case arg.rest && (!last || arg.kind != argString):
+			return fmt.Errorf("Command %v: only a last string argument may take the remaining tokens", command.name)
+		case !arg.optional && ii > 0 && command.args[ii-1].optional:
+			return fmt.Errorf("Command %v: required argument %v follows an optional argument", command.name, arg.name)
+		}
+	}
+	registry.commands[command.name] = &command
+	registry.names = append(registry.names, command.name)
+	return nil
+}
+
+//run carries out the command named by the first token, reporting a missing command or arguments which do not fit
+func (registry *commandRegistry) run(carpark *Carpark, tokens []string, errorAt func(token int, reason string) error, out *report) error {
+	command, ok := registry.commands[tokens[0]]
+	if !ok {
+		return errorAt(0, fmt.Sprintf("unknown command %q, try help", tokens[0]))
+	}
+	args, err := command.parse(tokens, errorAt)
+	if err != nil {
+		return err
+	}
+	return command.run(carpark, args, out)
+}
+
+//The commands of the carpark console
+func init() {
+	for _, command := range []consoleCommand{
+		{name: "create_parking_lot", args: []argument{{name: "slots", rest: true}},
+			help: "Create the carpark with the slots on each floor, then optionally first_fit, best_fit, nearest_exit[:<exit>], or random",
+			run:  runCreateParkingLot},
+		{name: "park", args: []argument{{name: "registration"}, {name: "colour"}, {name: "type"}, {name: "permits", optional: true}},
+			help: "Park a vehicle, with optional comma separated permits: accessible, ev, reserved, or emergency",
+			run:  runPark},
+		{name: "leave", args: []argument{{name: "slot", kind: argInt}},
+			help: "Remove the vehicle parked at a slot and charge it",
+			run:  runLeave},
+		{name: "leave_by_registration", args: []argument{{name: "registration"}},
+			help: "Remove a parked vehicle by its registration number and charge it",
+			run:  runLeaveByRegistration},
+		{name: "plan_compaction", args: []argument{{name: "slots", kind: argInt}},
+			help: "Show the fewest vehicle moves which would open a run of free slots",
+			run:  runCompaction(false)},
+		{name: "apply_compaction", args: []argument{{name: "slots", kind: argInt}},
+			help: "Move the fewest vehicles to open a run of free slots",
+			run:  runCompaction(true)},
+		{name: "set_zone", args: []argument{{name: "zone"}, {name: "first", kind: argInt}, {name: "last", kind: argInt}},
+			help: "Set the type of a range of slots: general, or the name of a vehicle type",
+			run:  runSetZone},
+		{name: "set_overflow", args: []argument{{name: "type"}, {name: "zones", optional: true, rest: true}},
+			help: "Set the zones a vehicle type may use once its own and general slots are taken, or none to stop it overflowing",
+			run:  runSetOverflow},
+		{name: "set_attribute", args: []argument{{name: "attribute"}, {name: "first", kind: argInt}, {name: "last", kind: argInt}},
+			help: "Give a range of slots an attribute: accessible, ev_charger, or reserved",
+			run:  runAttribute(true)},
+		{name: "clear_attribute", args: []argument{{name: "attribute"}, {name: "first", kind: argInt}, {name: "last", kind: argInt}},
+			help: "Take an attribute away from a range of slots",
+			run:  runAttribute(false)},
+		{name: "free_slots_with_attribute", args: []argument{{name: "attribute"}},
+			help: "List the free slots carrying an attribute",
+			run:  runFreeSlotsWithAttribute},
+		{name: "expand_parking_lot", args: []argument{{name: "slots", kind: argInt}},
+			help: "Add slots to the top floor",
+			run:  runResize(true)},
+		{name: "shrink_parking_lot", args: []argument{{name: "slots", kind: argInt}},
+			help: "Remove slots from the top floor",
+			run:  runResize(false)},
+		{name: "close_slot", args: []argument{{name: "slot", kind: argInt}, {name: "reason", optional: true, rest: true}},
+			help: "Take a free slot out of service, with an optional reason",
+			run:  runCloseSlot},
+		{name: "open_slot", args: []argument{{name: "slot", kind: argInt}},
+			help: "Return a closed slot to service",
+			run:  runOpenSlot},
+		{name: "quote", args: []argument{{name: "registration"}},
+			help: "Show the current charges for a parked vehicle",
+			run:  runQuote},
+		{name: "registration_numbers_for_cars_with_colour", args: []argument{{name: "colour"}},
+			help: "List the registration numbers of parked vehicles of a colour",
+			run:  runRegistrationNumbersForColour},
+		{name: "slot_numbers_for_cars_with_colour", args: []argument{{name: "colour"}},
+			help: "List the slots of parked vehicles of a colour",
+			run:  runSlotNumbersForColour},
+		{name: "slot_number_for_registration_number", args: []argument{{name: "registration"}},
+			help: "Show the slot of a parked vehicle",
+			run:  runSlotNumberForRegistration},
+		{name: "status",
+			help: "List the parked vehicles and closed slots",
+			run:  runStatus},
+		{name: "reserve", args: []argument{{name: "registration"}, {name: "type"}, {name: "from", kind: argTime}, {name: "to", kind: argTime}},
+			help: "Hold slots for a vehicle expected over a time window, keeping them from walk-ins from 30 minutes before it",
+			run:  runReserve},
+		{name: "reservations",
+			help: "List the slots held for expected vehicles",
+			run:  runReservations},
+		{name: "queue", args: []argument{{name: "order"}},
+			help: "Queue vehicles arriving at a full carpark in fifo or priority order, or turn them away when off",
+			run:  runQueue},
+		{name: "waiting",
+			help: "List the vehicles in the waiting queue in the order they would be admitted",
+			run:  runWaiting},
+		{name: "help", args: []argument{{name: "command", optional: true}},
+			help: "List the commands, or describe one",
+			run:  runHelp},
+		{name: "exit",
+			help: "End carpark operation",
+			run:  func(*Carpark, arguments, *report) error { return errExit }},
+	} {
+		if err := consoleCommands.register(command); err != nil {
+			panic(err)
+		}
+	}
+}
+
+func runCreateParkingLot(carpark *Carpark, args arguments, out *report) error {
+	layout, strategy, err := parseLayout(args.tokens("slots"))
+	if err != nil {
+		return err
+	}
+	if err := carpark.init(strategy, layout...); err != nil {
+		return err
+	}
+	out.set("slots", sum(layout))
+	out.set("floors", len(layout))
+	if carpark.multiLevel() {
+		out.printf("Created a parking lot with %v slots on %v floors", sum(layout), len(layout))
+	} else {
+		out.printf("Created a parking lot with %v slots", layout[0])
+	}
+	return nil
+}
+
+func runPark(carpark *Carpark, args arguments, out *report) error {
+	class, err := carpark.types().lookup(args.str("type"))
+	if err != nil {
+		return err
+	}
+	vehicle := class.vehicle(args.str("registration"), args.str("colour"))
+	if args.has("permits") {
+		*vehicle.getPermits(), err = parseAttributes(strings.Split(args.str("permits"), ","), permitNames, errUnknownPermit)
+		if err != nil {
+			return err
+		}
+	}
+	slotNo, level, err := carpark.insertCar(vehicle)
+	if err != nil {
+		return err
+	}
+	out.set("registration", *vehicle.getRegistration())
+	if slotNo == 0 {
+		out.set("queued", true)
+		out.printf("Sorry, parking lot is full, vehicle added to the waiting queue")
+		return nil
+	}
+	out.set("slot", slotNo)
+	out.set("floor", level)
+	if carpark.multiLevel() {
+		out.printf("Allocated slot number: %v on floor %v", slotNo, level)
+	} else {
+		out.printf("Allocated slot number: %v", slotNo)
+	}
+	return nil
+}
+
+func runLeave(carpark *Carpark, args arguments, out *report) error {
+	receipt, err := carpark.removeCar(args.number("slot"))
+	if err != nil {
+		return err
+	}
+	out.printf("Slot number %v is free", slotLabels(carpark, args.number("slot"))[0])
+	printReceipt(out, receipt)
+	printAdmitted(out, carpark, receipt.admitted)
+	return nil
+}
+
+func runLeaveByRegistration(carpark *Carpark, args arguments, out *report) error {
+	receipt, err := carpark.removeCarWithRegistration(args.str("registration"))
+	if err != nil {
+		return err
+	}
+	if len(receipt.slots) == 1 {
+		out.printf("Slot number %v is free", slotLabels(carpark, receipt.slots...)[0])
+	} else {
+		out.printf("Slot numbers %v are free", strings.Join(slotLabels(carpark, receipt.slots...), ", "))
+	}
+	printReceipt(out, receipt)
+	printAdmitted(out, carpark, receipt.admitted)
+	return nil
+}
+
+//runCompaction plans the vehicle moves opening a run of free slots, and carries them out when 'apply' is set
+func runCompaction(apply bool) func(carpark *Carpark, args arguments, out *report) error {
+	return func(carpark *Carpark, args arguments, out *report) error {
+		var plan *compactionPlan
+		var err error
+		if apply {
+			plan, err = carpark.applyCompaction(args.number("slots"))
+		} else {
+			plan, err = carpark.planCompaction(args.number("slots"))
+		}
+		if err != nil {
+			return err
+		}
+		printCompaction(out, carpark, plan, apply)
+		printAdmitted(out, carpark, plan.admitted)
+		return nil
+	}
+}
+
+func runSetZone(carpark *Carpark, args arguments, out *report) error {
+	firstSlot, lastSlot := args.number("first"), args.number("last")
+	admitted, err := carpark.setZone(args.str("zone"), firstSlot, lastSlot)
+	if err != nil {
+		return err
+	}
+	labels := slotLabels(carpark, firstSlot, lastSlot)
+	out.printf("Slots %v to %v are %v slots", labels[0], labels[1], strings.ToLower(args.str("zone")))
+	out.set("zone", strings.ToLower(args.str("zone")))
+	out.set("first", firstSlot)
+	out.set("last", lastSlot)
+	printAdmitted(out, carpark, admitted)
+	return nil
+}
+
+func runSetOverflow(carpark *Carpark, args arguments, out *report) error {
+	vehicleType, zones := args.str("type"), args.tokens("zones")
+	admitted, err := carpark.setOverflow(vehicleType, zones)
+	if err != nil {
+		return err
+	}
+	if len(zones) == 0 {
+		out.printf("%v may not overflow", strings.Title(strings.ToLower(vehicleType)))
+	} else {
+		out.printf("%v may overflow into %v slots", strings.Title(strings.ToLower(vehicleType)), strings.ToLower(strings.Join(zones, ", ")))
+	}
+	overflow := []string{}
+	for _, zone := range zones {
+		overflow = append(overflow, strings.ToLower(zone))
+	}
+	out.set("type", strings.ToLower(vehicleType))
+	out.set("zones", overflow)
+	printAdmitted(out, carpark, admitted)
+	return nil
+}
+
+//runAttribute gives an attribute to a range of slots when 'set' is set, and otherwise takes it away
+func runAttribute(set bool) func(carpark *Carpark, args arguments, out *report) error {
+	return func(carpark *Carpark, args arguments, out *report) error {
+		name, firstSlot, lastSlot := args.str("attribute"), args.number("first"), args.number("last")
+		labels := slotLabels(carpark, firstSlot, lastSlot)
+		out.set("attribute", strings.ToLower(name))
+		out.set("first", firstSlot)
+		out.set("last", lastSlot)
+		if set {
+			if err := carpark.setAttribute(name, firstSlot, lastSlot); err != nil {
+				return err
+			}
+			out.printf("Slots %v to %v have attribute %v", labels[0], labels[1], strings.ToLower(name))
+		} else {
+			admitted, err := carpark.clearAttribute(name, firstSlot, lastSlot)
+			if err != nil {
+				return err
+			}
+			out.printf("Slots %v to %v no longer have attribute %v", labels[0], labels[1], strings.ToLower(name))
+			printAdmitted(out, carpark, admitted)
+		}
+		return nil
+	}
+}
+
+func runFreeSlotsWithAttribute(carpark *Carpark, args arguments, out *report) error {
+	slots, err := carpark.getFreeSlotsWithAttribute(args.str("attribute"))
+	if err != nil {
+		return err
+	}
+	out.list("Slot No.", slotLabels(carpark, slots...))
+	return nil
+}
+
+//runResize adds slots to the top floor when 'expand' is set, and otherwise removes them
+func runResize(expand bool) func(carpark *Carpark, args arguments, out *report) error {
+	return func(carpark *Carpark, args arguments, out *report) error {
+		var admitted []Vehicle
+		var err error
+		if expand {
+			admitted, err = carpark.expand(args.number("slots"))
+		} else {
+			err = carpark.shrink(args.number("slots"))
+		}
+		if err != nil {
+			return err
+		}
+		out.printf("Resized the parking lot to %v slots", carpark.getSize())
+		out.set("slots", carpark.getSize())
+		printAdmitted(out, carpark, admitted)
+		return nil
+	}
+}
+
+func runCloseSlot(carpark *Carpark, args arguments, out *report) error {
+	reason := strings.Join(args.tokens("reason"), " ")
+	if reason == "" {
+		reason = defaultClosure
+	}
+	if err := carpark.closeSlot(args.number("slot"), reason); err != nil {
+		return err
+	}
+	out.printf("Slot number %v is closed", slotLabels(carpark, args.number("slot"))[0])
+	out.set("slot", args.number("slot"))
+	out.set("reason", reason)
+	return nil
+}
+
+func runOpenSlot(carpark *Carpark, args arguments, out *report) error {
+	admitted, err := carpark.openSlot(args.number("slot"))
+	if err != nil {
+		return err
+	}
+	out.printf("Slot number %v is open", slotLabels(carpark, args.number("slot"))[0])
+	out.set("slot", args.number("slot"))
+	printAdmitted(out, carpark, admitted)
+	return nil
+}
+
+func runQuote(carpark *Carpark, args arguments, out *report) error {
+	receipt, err := carpark.quote(args.str("registration"))
+	if err != nil {
+		return err
+	}
+	printReceipt(out, receipt)
+	return nil
+}
+
+func runRegistrationNumbersForColour(carpark *Carpark, args arguments, out *report) error {
+	_, registration, err := carpark.getCarsWithColour(args.str("colour"))
+	if err != nil {
+		return err
+	}
+	out.list("Registration No", registration)
+	return nil
+}
+
+func runSlotNumbersForColour(carpark *Carpark, args arguments, out *report) error {
+	slots, _, err := carpark.getCarsWithColour(args.str("colour"))
+	if err != nil {
+		return err
+	}
+	out.list("Slot No.", slotLabels(carpark, slots...))
+	return nil
+}
+
+func runSlotNumberForRegistration(carpark *Carpark, args arguments, out *report) error {
+	slotNo, err := carpark.getCarWithRegistrationNo(args.str("registration"))
+	if err != nil {
+		return err
+	}
+	out.println(slotLabels(carpark, slotNo)[0])
+	out.set("slot", slotNo)
+	out.set("floor", carpark.getLevel(slotNo))
+	return nil
+}
+
+func runStatus(carpark *Carpark, args arguments, out *report) error {
+	vehicles := carpark.getStatus()
+	closed := carpark.getClosed()
+	var closedSlots []int
+	for slotNo := range closed {
+		closedSlots = append(closedSlots, slotNo)
+	}
+	sort.Ints(closedSlots)
+	headers := []string{"Slot No.", "Registration No", "Colour", "Type"}
+	if carpark.multiLevel() {
+		headers = append([]string{"Floor"}, headers...)
+	}
+	var rows [][]string
+	row := func(slotNo int, cells ...string) {
+		cells = append([]string{strconv.Itoa(slotNo)}, cells...)
+		if carpark.multiLevel() {
+			cells = append([]string{strconv.Itoa(carpark.getLevel(slotNo))}, cells...)
+		}
+		rows = append(rows, cells)
+	}
+	for _, vehicle := range vehicles {
+		for len(closedSlots) > 0 && closedSlots[0] < *vehicle.getSlot() {
+			row(closedSlots[0], "", "", "Closed: "+closed[closedSlots[0]])
+			closedSlots = closedSlots[1:]
+		}
+		row(*vehicle.getSlot(), *vehicle.getRegistration(), *vehicle.getColour(), vehicle.getType())
+	}
+	for _, slotNo := range closedSlots {
+		row(slotNo, "", "", "Closed: "+closed[slotNo])
+	}
+	out.table(headers, rows)
+	return nil
+}
+
+func runReserve(carpark *Carpark, args arguments, out *report) error {
+	reservation, err := carpark.reserve(args.str("registration"), args.str("type"), args.when("from"), args.when("to"))
+	if err != nil {
+		return err
+	}
+	out.set("registration", reservation.registration)
+	out.set("slot", reservation.slot)
+	out.set("floor", carpark.getLevel(reservation.slot))
+	if carpark.multiLevel() {
+		out.printf("Reserved slot number: %v on floor %v", reservation.slot, carpark.getLevel(reservation.slot))
+	} else {
+		out.printf("Reserved slot number: %v", reservation.slot)
+	}
+	return nil
+}
+
+func runReservations(carpark *Carpark, args arguments, out *report) error {
+	var rows [][]string
+	for _, reservation := range carpark.getReservations() {
+		rows = append(rows, []string{slotLabels(carpark, reservation.slot)[0], reservation.registration,
+			strings.Title(reservation.vehicleType), reservation.from.Format(reservationLayout), reservation.to.Format(reservationLayout)})
+	}
+	out.table([]string{"Slot No.", "Registration No", "Type", "From", "To"}, rows)
+	return nil
+}
+
+func runQueue(carpark *Carpark, args arguments, out *report) error {
+	if err := carpark.setQueue(args.str("order")); err != nil {
+		return err
+	}
+	out.printf("Waiting queue is %v", strings.ToLower(args.str("order")))
+	out.set("order", strings.ToLower(args.str("order")))
+	return nil
+}
+
+func runWaiting(carpark *Carpark, args arguments, out *report) error {
+	var rows [][]string
+	for ii, vehicle := range carpark.getWaiting() {
+		rows = append(rows, []string{strconv.Itoa(ii + 1), *vehicle.getRegistration(), *vehicle.getColour(), vehicle.getType()})
+	}
+	out.table([]string{"Position", "Registration No", "Colour", "Type"}, rows)
+	return nil
+}
+
+func runHelp(carpark *Carpark, args arguments, out *report) error {
+	if args.has("command") {
+		command, ok := consoleCommands.commands[strings.ToLower(args.str("command"))]
+		if !ok {
+			return errUnknownCommand
+		}
+		out.printf("Usage: %v", command.usage())
+		out.println(command.help)
+		return nil
+	}
+	var rows [][]string
+	for _, name := range consoleCommands.names {
+		command := consoleCommands.commands[name]
+		rows = append(rows, []string{command.usage(), command.help})
+	}
+	out.table([]string{"Command", "Description"}, rows)
+	return nil
+}
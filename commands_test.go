@@ -0,0 +1,68 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCommand_parse(t *testing.T) {
+	command := &consoleCommand{name: "close_range", args: []argument{{name: "first", kind: argInt}, {name: "last", kind: argInt, optional: true},
+		{name: "reason", optional: true, rest: true}}}
+	errorAt := (&commandReader{start: 4}).errorAt
+
+	tests := []struct {
+		name    string
+		tokens  []string
+		want    arguments
+		wantErr string
+	}{
+		{name: "Required argument only", tokens: []string{"close_range", "3"}, want: arguments{"first": 3, "reason": []string{}}},
+		{name: "Every argument", tokens: []string{"close_range", "3", "5", "wet", "paint"}, want: arguments{"first": 3, "last": 5, "reason": []string{"wet", "paint"}}},
+		{name: "Missing argument", tokens: []string{"close_range"}, wantErr: "Line 4, token 2: missing <first>, usage: close_range <first> [last] [reason...]"},
+		{name: "Not a number", tokens: []string{"close_range", "3", "five"}, wantErr: `Line 4, token 3: "five" is not a number`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := command.parse(tt.tokens, errorAt)
+			if (err != nil || tt.wantErr != "") && (err == nil || err.Error() != tt.wantErr) {
+				t.Fatalf("consoleCommand.parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("consoleCommand.parse() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	extra := &consoleCommand{name: "status"}
+	if _, err := extra.parse([]string{"status", "all"}, errorAt); err == nil || err.Error() != `Line 4, token 2: unexpected argument "all", usage: status` {
+		t.Errorf("consoleCommand.parse() error = %v", err)
+	}
+}
+
+func Test_commandRegistry_register(t *testing.T) {
+	run := func(*Carpark, arguments, *report) error { return nil }
+	registry := &commandRegistry{commands: make(map[string]*consoleCommand)}
+
+	tests := []struct {
+		name    string
+		command consoleCommand
+		wantErr bool
+	}{
+		{name: "Valid command", command: consoleCommand{name: "Count", args: []argument{{name: "colour", optional: true}}, run: run}},
+		{name: "Verb already taken", command: consoleCommand{name: "count", run: run}, wantErr: true},
+		{name: "Verb with a space", command: consoleCommand{name: "count cars", run: run}, wantErr: true},
+		{name: "Nothing to run", command: consoleCommand{name: "noop"}, wantErr: true},
+		{name: "Required after optional", command: consoleCommand{name: "move", args: []argument{{name: "from", optional: true}, {name: "to"}}, run: run}, wantErr: true},
+		{name: "Remaining tokens not last", command: consoleCommand{name: "note", args: []argument{{name: "words", rest: true}, {name: "slot"}}, run: run}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := registry.register(tt.command); (err != nil) != tt.wantErr {
+				t.Errorf("commandRegistry.register() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+	if !reflect.DeepEqual(registry.names, []string{"count"}) {
+		t.Errorf("commandRegistry.names = %v, want [count]", registry.names)
+	}
+}
@@ -0,0 +1,458 @@
+package main
+
+import (
+	"errors"
+	"sort"
+	"strings"
+)
+
+//errCannotCompact reports that no vehicle moves can open the requested run of slots
+var errCannotCompact = errors.New("Sorry, no vehicle moves can free that many consecutive slots")
+
+//move relocates a parked vehicle
+type move struct {
+	vehicle Vehicle //Vehicle to be moved
+	from    int     //First slot the vehicle occupies now
+	to      int     //First slot the vehicle will occupy
+}
+
+//compactionPlan lists the vehicle moves which open a run of free slots
+type compactionPlan struct {
+	start    int       //First slot of the run
+	slots    int       //Number of slots in the run
+	moves    []move    //Vehicle moves opening the run, none when the run is already free
+	admitted []Vehicle //Waiting vehicles parked once the moves were made
+}
+
+//Compute the fewest vehicle moves which would open 'slotsNeeded' consecutive free slots on one floor
+func (carpark *Carpark) planCompaction(slotsNeeded int) (*compactionPlan, error) {
+	carpark.mu.Lock()
+	defer carpark.mu.Unlock()
+
+	if err := carpark.initStatus(); err != nil {
+		return nil, err
+	}
+	return carpark.plan(slotsNeeded)
+}
+
+//Open 'slotsNeeded' consecutive free slots on one floor with the fewest vehicle moves, admit waiting vehicles
+//into the slots opened, and return the moves made and vehicles admitted
+func (carpark *Carpark) applyCompaction(slotsNeeded int) (*compactionPlan, error) {
+	carpark.mu.Lock()
+	defer carpark.mu.Unlock()
+
+	if err := carpark.initStatus(); err != nil {
+		return nil, err
+	}
+	plan, err := carpark.plan(slotsNeeded)
+	if err != nil || len(plan.moves) == 0 {
+		return plan, err
+	}
+	compactEvent := &event{Type: eventCompact, Time: carpark.now()}
+	for _, move := range plan.moves {
+		compactEvent.Moves = append(compactEvent.Moves, eventMove{From: move.from, To: move.to})
+	}
+	if err := carpark.record(compactEvent); err != nil {
+		return nil, err
+	}
+	carpark.relocate(plan.moves)
+	carpark.changed()
+	plan.admitted = carpark.admit()
+	return plan, nil
+}
+
+//Find the run of slots which the fewest vehicle moves can free, preferring lower slots. Vehicles parked outside
+//the run may move as well, to make room for those displaced
+func (carpark *Carpark) plan(slotsNeeded int) (*compactionPlan, error) {
+	if slotsNeeded <= 0 {
+		return nil, errCannotCompact
+	}
+	//Every vehicle parked must still fit in the slots in service outside the run
+	occupied, inService := 0, 0
+	for _, vehicle := range carpark.Map {
+		occupied += vehicle.getSlotsNeeded()
+	}
+	for _, floor := range carpark.floors {
+		inService += floor.maxSlot - floor.firstSlot + 1 - len(floor.closed)
+	}
+	if occupied > inService-slotsNeeded {
+		return nil, errCannotCompact
+	}
+
+	//Try the runs displacing the fewest vehicles first, as no run can be freed with fewer moves than it displaces
+	type run struct {
+		start     int //First slot of the run
+		displaced int //Number of vehicles parked in the run
+	}
+	var runs []run
+	for _, floor := range carpark.floors {
+		for start := floor.firstSlot; start+slotsNeeded-1 <= floor.maxSlot; start++ {
+			if !carpark.closedIn(start, slotsNeeded) {
+				runs = append(runs, run{start: start, displaced: len(carpark.overlapping(start, slotsNeeded))})
+			}
+		}
+	}
+	sort.SliceStable(runs, func(i, j int) bool { return runs[i].displaced < runs[j].displaced })
+
+	//Allow one more move at a time, so that the first runs freed take the fewest moves
+	for limit := 0; len(runs) > 0 && limit <= len(carpark.Map); limit++ {
+		var best *compactionPlan
+		for _, run := range runs {
+			if run.displaced > limit {
+				break
+			}
+			moves, ok := carpark.rearrange(run.start, slotsNeeded, limit)
+			if ok && (best == nil || len(moves) < len(best.moves) || len(moves) == len(best.moves) && run.start < best.start) {
+				best = &compactionPlan{start: run.start, slots: slotsNeeded, moves: moves}
+			}
+		}
+		if best != nil {
+			return best, nil
+		}
+	}
+	return nil, errCannotCompact
+}
+
+//List the vehicles occupying any of 'slots' slots from 'start'
+func (carpark *Carpark) overlapping(start int, slots int) []Vehicle {
+	var vehicles []Vehicle
+	for _, vehicle := range carpark.Map {
+		if carpark.overlaps(vehicle, start, slots) {
+			vehicles = append(vehicles, vehicle)
+		}
+	}
+	return vehicles
+}
+
+//Check whether a vehicle occupies any of 'slots' slots from 'start'
+func (carpark *Carpark) overlaps(vehicle Vehicle, start int, slots int) bool {
+	return *vehicle.getSlot() < start+slots && *vehicle.getSlot()+vehicle.getSlotsNeeded() > start
+}
+
+//rearrangement searches for the fewest vehicle moves freeing a run of slots. It scans the slots of every floor
+//in order, deciding whether the vehicle parked at each slot stays and which kind of vehicle, if any, moves in,
+//while counting for each class of interchangeable vehicles how many moved out but have not yet moved in again
+type rearrangement struct {
+	carpark  *Carpark
+	start    int               //First slot of the run to be freed
+	slots    int               //Number of slots in the run
+	order    []int             //Every slot of the carpark, floor by floor
+	classes  []Vehicle         //A vehicle of each class of vehicles which may park in the same positions
+	classOf  map[Vehicle]int   //Class of each parked vehicle
+	tiers    [][]int           //Zone tier of the position starting at each slot for each class, or -1 when it may not park there
+	later    [][]int           //Number of vehicles of each class parked from each slot of the scan onwards
+	inRun    []int             //Number of vehicles parked in the run from each slot of the scan onwards
+	demand   []int             //Number of slots taken by the vehicles parked from each slot of the scan onwards
+	room     []int             //Number of slots in service outside the run from each slot of the scan onwards
+	radix    []int             //Multipliers encoding the vehicles awaiting a new position as a single number
+	limit    int               //Most moves worth finding
+	outcomes []map[int]outcome //Best outcome from each slot of the scan, keyed by the vehicles awaiting a new position
+}
+
+//outcome is the best way to finish the scan of a rearrangement from a slot
+type outcome struct {
+	ok     bool //Whether the scan can finish with every vehicle parked
+	moves  int  //Number of vehicles moved
+	tiers  int  //Sum of the zone tiers of the positions vehicles move to, preferring the zones they are meant for
+	drop   bool //Whether the vehicle parked at the slot moves
+	choice int  //Class of the vehicle moving into the slot, or keep or leave
+}
+
+//Choices of an outcome other than a vehicle moving into the slot
+const (
+	keepVehicle = -2 //The vehicle parked at the slot stays
+	leaveEmpty  = -1 //The slot is left empty
+)
+
+//Find the fewest vehicle moves, no more than 'limit', leaving 'slots' slots from 'start' free, moving vehicles
+//only into free slots in service which are not held, in zones and slots they may use, and preferring lower slots
+func (carpark *Carpark) rearrange(start int, slots int, limit int) ([]move, bool) {
+	search := &rearrangement{carpark: carpark, start: start, slots: slots, limit: limit, classOf: make(map[Vehicle]int)}
+	for _, floor := range carpark.floors {
+		for slotNo := floor.firstSlot; slotNo <= floor.maxSlot; slotNo++ {
+			search.order = append(search.order, slotNo)
+		}
+	}
+	var counts []int
+	for _, slotNo := range search.order {
+		vehicle, ok := carpark.Map[slotNo]
+		if !ok {
+			continue
+		}
+		class := -1
+		for ii, other := range search.classes {
+			if strings.EqualFold(other.getType(), vehicle.getType()) && *other.getPermits() == *vehicle.getPermits() {
+				class = ii
+			}
+		}
+		if class < 0 {
+			class = len(search.classes)
+			search.classes = append(search.classes, vehicle)
+			counts = append(counts, 0)
+		}
+		search.classOf[vehicle] = class
+		counts[class]++
+	}
+	search.radix = make([]int, len(counts))
+	for class := range counts {
+		search.radix[class] = 1
+		if class > 0 {
+			search.radix[class] = search.radix[class-1] * (2*counts[class-1] + 1)
+		}
+	}
+	search.tiers = make([][]int, len(search.classes))
+	for class := range search.classes {
+		search.tiers[class] = search.positions(class)
+	}
+	search.later = make([][]int, len(search.classes))
+	for class := range search.classes {
+		search.later[class] = make([]int, len(search.order)+1)
+		for ii := len(search.order) - 1; ii >= 0; ii-- {
+			search.later[class][ii] = search.later[class][ii+1]
+			if vehicle, ok := carpark.Map[search.order[ii]]; ok && search.classOf[vehicle] == class {
+				search.later[class][ii]++
+			}
+		}
+	}
+	search.inRun = make([]int, len(search.order)+1)
+	for ii := len(search.order) - 1; ii >= 0; ii-- {
+		search.inRun[ii] = search.inRun[ii+1]
+		if vehicle, ok := carpark.Map[search.order[ii]]; ok && carpark.overlaps(vehicle, start, slots) {
+			search.inRun[ii]++
+		}
+	}
+	search.demand = make([]int, len(search.order)+1)
+	search.room = make([]int, len(search.order)+1)
+	for ii := len(search.order) - 1; ii >= 0; ii-- {
+		slotNo := search.order[ii]
+		search.demand[ii], search.room[ii] = search.demand[ii+1], search.room[ii+1]
+		if vehicle, ok := carpark.Map[slotNo]; ok {
+			search.demand[ii] += vehicle.getSlotsNeeded()
+		}
+		if _, closed := carpark.floorOf(slotNo).closed[slotNo]; !closed && (slotNo < start || slotNo >= start+slots) {
+			search.room[ii]++
+		}
+	}
+	search.outcomes = make([]map[int]outcome, len(search.order)+1)
+	for ii := range search.outcomes {
+		search.outcomes[ii] = make(map[int]outcome)
+	}
+	waiting := make([]int, len(search.classes))
+	if best := search.solve(0, waiting); !best.ok || best.moves > limit {
+		return nil, false
+	}
+	return search.moves(), true
+}
+
+//List the zone tier of the position starting at each slot of the scan for a class of vehicle,
+//or -1 where the vehicle may not move to
+func (search *rearrangement) positions(class int) []int {
+	carpark, vehicle := search.carpark, search.classes[class]
+	zoneTiers := carpark.zoneTiers(vehicle)
+	usable := func(slotNo int) int {
+		if slotNo >= search.start && slotNo < search.start+search.slots {
+			return -1
+		}
+		if _, closed := carpark.floorOf(slotNo).closed[slotNo]; closed || !carpark.permitted(vehicle, slotNo, 1) {
+			return -1
+		}
+		for _, reservation := range carpark.reservations {
+			if slotNo >= reservation.slot && slotNo < reservation.slot+reservation.slots {
+				return -1
+			}
+		}
+		for tier, zones := range zoneTiers {
+			if zones[carpark.zoneOf(slotNo)] {
+				return tier
+			}
+		}
+		return -1
+	}
+	tiers := make([]int, len(search.order))
+	for ii, slotNo := range search.order {
+		tiers[ii] = -1
+		floor := carpark.floorOf(slotNo)
+		if slotNo+vehicle.getSlotsNeeded()-1 > floor.maxSlot {
+			continue
+		}
+		highest := 0
+		for offset := 0; offset < vehicle.getSlotsNeeded(); offset++ {
+			tier := usable(slotNo + offset)
+			if tier < 0 {
+				highest = -1
+				break
+			}
+			if tier > highest {
+				highest = tier
+			}
+		}
+		tiers[ii] = highest
+	}
+	return tiers
+}
+
+//Find the best outcome from a slot of the scan, given the vehicles of each class awaiting a new position,
+//negative where vehicles moved in before the vehicles they replace were reached
+func (search *rearrangement) solve(ii int, waiting []int) outcome {
+	if ii == len(search.order) {
+		for _, count := range waiting {
+			if count != 0 {
+				return outcome{}
+			}
+		}
+		return outcome{ok: true}
+	}
+	key, ok := search.key(ii, waiting)
+	if !ok {
+		return outcome{}
+	}
+	if known, ok := search.outcomes[ii][key]; ok {
+		return known
+	}
+
+	var best outcome
+	consider := func(next outcome, moves int, tiers int, drop bool, choice int) {
+		if !next.ok {
+			return
+		}
+		moves, tiers = moves+next.moves, tiers+next.tiers
+		if !best.ok || moves < best.moves || moves == best.moves && tiers < best.tiers {
+			best = outcome{ok: true, moves: moves, tiers: tiers, drop: drop, choice: choice}
+		}
+	}
+	slotNo := search.order[ii]
+	vehicle, parked := search.carpark.Map[slotNo]
+	if parked && !search.carpark.overlaps(vehicle, search.start, search.slots) {
+		consider(search.solve(ii+vehicle.getSlotsNeeded(), waiting), 0, 0, false, keepVehicle)
+	}
+	moved := 0
+	if parked {
+		waiting[search.classOf[vehicle]]++
+		moved = 1
+	}
+	for class, tiers := range search.tiers {
+		if tiers[ii] < 0 {
+			continue
+		}
+		//Every vehicle parked in the new position moves out as well
+		slotsNeeded, displaced := search.classes[class].getSlotsNeeded(), 0
+		for slot := slotNo + 1; slot < slotNo+slotsNeeded; slot++ {
+			if other, ok := search.carpark.Map[slot]; ok {
+				waiting[search.classOf[other]]++
+				displaced++
+			}
+		}
+		waiting[class]--
+		consider(search.solve(ii+slotsNeeded, waiting), moved+displaced, tiers[ii], parked, class)
+		waiting[class]++
+		for slot := slotNo + 1; slot < slotNo+slotsNeeded; slot++ {
+			if other, ok := search.carpark.Map[slot]; ok {
+				waiting[search.classOf[other]]--
+			}
+		}
+	}
+	consider(search.solve(ii+1, waiting), moved, 0, parked, leaveEmpty)
+	if parked {
+		waiting[search.classOf[vehicle]]--
+	}
+	search.outcomes[ii][key] = best
+	return best
+}
+
+//Encode the vehicles of each class awaiting a new position at a slot of the scan, reporting false when more
+//vehicles moved in than remain to be replaced, when the vehicles awaiting a position and those parked further on
+//cannot fit in the slots left, or when the moves already made and still needed exceed the limit: each vehicle
+//which moved out and awaits a position has moved, and each vehicle which moved in, like each vehicle parked in
+//the run further on, needs a vehicle still to move out
+func (search *rearrangement) key(ii int, waiting []int) (int, bool) {
+	key, moved, needed, demand := 0, 0, 0, search.demand[ii]
+	for class, count := range waiting {
+		if -count > search.later[class][ii] {
+			return 0, false
+		}
+		demand += count * search.classes[class].getSlotsNeeded()
+		if count < 0 {
+			needed -= count
+		} else {
+			moved += count
+		}
+		key += (count + search.later[class][0]) * search.radix[class]
+	}
+	if search.inRun[ii] > needed {
+		needed = search.inRun[ii]
+	}
+	return key, demand <= search.room[ii] && moved+needed <= search.limit
+}
+
+//Follow the best outcomes of the scan from the first slot, and pair the vehicles of each class moving out
+//with the positions vehicles of that class move into, both in slot order
+func (search *rearrangement) moves() []move {
+	waiting := make([]int, len(search.classes))
+	leaving := make([][]Vehicle, len(search.classes))
+	arriving := make([][]int, len(search.classes))
+	leave := func(vehicle Vehicle) {
+		class := search.classOf[vehicle]
+		leaving[class] = append(leaving[class], vehicle)
+		waiting[class]++
+	}
+	for ii := 0; ii < len(search.order); {
+		key, _ := search.key(ii, waiting)
+		best := search.outcomes[ii][key]
+		slotNo := search.order[ii]
+		if best.drop {
+			leave(search.carpark.Map[slotNo])
+		}
+		switch best.choice {
+		case keepVehicle:
+			ii += search.carpark.Map[slotNo].getSlotsNeeded()
+		case leaveEmpty:
+			ii++
+		default:
+			slotsNeeded := search.classes[best.choice].getSlotsNeeded()
+			for slot := slotNo + 1; slot < slotNo+slotsNeeded; slot++ {
+				if other, ok := search.carpark.Map[slot]; ok {
+					leave(other)
+				}
+			}
+			arriving[best.choice] = append(arriving[best.choice], slotNo)
+			waiting[best.choice]--
+			ii += slotsNeeded
+		}
+	}
+	var moves []move
+	for class := range search.classes {
+		for ii, vehicle := range leaving[class] {
+			moves = append(moves, move{vehicle: vehicle, from: *vehicle.getSlot(), to: arriving[class][ii]})
+		}
+	}
+	sort.Slice(moves, func(i, j int) bool { return moves[i].from < moves[j].from })
+	return moves
+}
+
+//Map every slot of the carpark to whether it is free of vehicles and in service
+func (carpark *Carpark) freeSlots() map[int]bool {
+	free := make(map[int]bool)
+	for _, floor := range carpark.floors {
+		for slotNo := floor.firstSlot; slotNo <= floor.maxSlot; slotNo++ {
+			_, closed := floor.closed[slotNo]
+			free[slotNo] = !closed
+		}
+	}
+	for slotNo, vehicle := range carpark.Map {
+		for ii := 0; ii < vehicle.getSlotsNeeded(); ii++ {
+			free[slotNo+ii] = false
+		}
+	}
+	return free
+}
+
+//Move vehicles to their new slots, vacating every old slot before filling any new one
+func (carpark *Carpark) relocate(moves []move) {
+	for _, move := range moves {
+		carpark.remove(move.from)
+	}
+	for _, move := range moves {
+		carpark.floorOf(move.to).occupy(move.to, move.vehicle.getSlotsNeeded())
+		carpark.place(move.vehicle, move.to, *move.vehicle.getArrival())
+	}
+}
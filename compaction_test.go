@@ -0,0 +1,181 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+//scatter creates a carpark of 6 slots holding motorcycles at slots 1, 3, and 5
+func scatter(carpark *Carpark) *Carpark {
+	carpark.init(nil, 6)
+	for ii := 1; ii <= 6; ii++ {
+		carpark.insertCar(defaultVehicleTypes.newVehicle("motorcycle", fmt.Sprintf("KA-01-MM-000%v", ii), "Black"))
+	}
+	for _, slotNo := range []int{2, 4, 6} {
+		carpark.removeCar(slotNo)
+	}
+	return carpark
+}
+
+func TestCarpark_planCompaction(t *testing.T) {
+	tests := []struct {
+		name      string
+		slots     int
+		wantStart int
+		wantMoves map[int]int
+		wantErr   error
+	}{
+		{name: "Run already free", slots: 1, wantStart: 2, wantMoves: map[int]int{}},
+		{name: "One move", slots: 3, wantStart: 2, wantMoves: map[int]int{3: 6}},
+		{name: "Not enough free slots elsewhere", slots: 4, wantErr: errCannotCompact},
+		{name: "Longer than the carpark", slots: 7, wantErr: errCannotCompact},
+		{name: "No slots", slots: 0, wantErr: errCannotCompact},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			carpark := scatter(&Carpark{})
+			got, err := carpark.planCompaction(tt.slots)
+			if err != tt.wantErr {
+				t.Fatalf("Carpark.planCompaction() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			gotMoves := make(map[int]int)
+			for _, move := range got.moves {
+				gotMoves[move.from] = move.to
+			}
+			if got.start != tt.wantStart || !reflect.DeepEqual(gotMoves, tt.wantMoves) {
+				t.Errorf("Carpark.planCompaction() start = %v, moves = %v, want %v, %v", got.start, gotMoves, tt.wantStart, tt.wantMoves)
+			}
+			if len(carpark.Map) != 3 || carpark.Map[3] == nil {
+				t.Errorf("Carpark.planCompaction() moved vehicles, Map = %v", carpark.Map)
+			}
+		})
+	}
+}
+
+func TestCarpark_planCompactionBacktracks(t *testing.T) {
+	vehicleTypes := vehicleRegistry{"motorcycle": defaultVehicleTypes["motorcycle"]}
+	for _, slots := range []int{2, 3, 4, 5} {
+		name := fmt.Sprintf("size%v", slots)
+		vehicleTypes[name] = &vehicleClass{Name: name, Slots: slots}
+	}
+
+	//Slots 1 to 10 and 12 to 21 are free, slots 11 and 22 are closed, and slots 23 to 42 hold vehicles
+	//which only fit back into the free slots as 5+3+2 and 4+4+2, not largest first at the lowest slots
+	carpark := &Carpark{vehicleTypes: vehicleTypes}
+	carpark.init(nil, 42)
+	for ii := 1; ii <= 22; ii++ {
+		carpark.insertCar(vehicleTypes.newVehicle("motorcycle", fmt.Sprintf("KA-01-MM-%04v", ii), "Black"))
+	}
+	for ii, slots := range []int{5, 4, 4, 3, 2, 2} {
+		carpark.insertCar(vehicleTypes.newVehicle(fmt.Sprintf("size%v", slots), fmt.Sprintf("KA-01-VV-%04v", ii), "White"))
+	}
+	for ii := 1; ii <= 22; ii++ {
+		carpark.removeCar(ii)
+	}
+	carpark.closeSlot(11, "")
+	carpark.closeSlot(22, "")
+
+	got, err := carpark.planCompaction(20)
+	if err != nil {
+		t.Fatalf("Carpark.planCompaction() error = %v", err)
+	}
+	if got.start != 23 || len(got.moves) != 6 {
+		t.Fatalf("Carpark.planCompaction() start = %v, moves = %v, want 23, 6 moves", got.start, len(got.moves))
+	}
+	taken := make(map[int]bool)
+	for _, move := range got.moves {
+		for slotNo := move.to; slotNo < move.to+move.vehicle.getSlotsNeeded(); slotNo++ {
+			if taken[slotNo] || slotNo == 11 || slotNo > 21 {
+				t.Errorf("Carpark.planCompaction() moves %v to slot %v, which is not free", *move.vehicle.getRegistration(), move.to)
+			}
+			taken[slotNo] = true
+		}
+	}
+}
+
+func TestCarpark_planCompactionChains(t *testing.T) {
+	//Cars fill slots 1 and 2, 4 and 5, and so on up to 58 and 59, leaving single free slots in between, so that
+	//no run of 20 slots opens by moving only the cars parked in it: cars outside the run shift up to make room
+	carpark := &Carpark{}
+	carpark.init(nil, 60)
+	for ii := 0; ii < 20; ii++ {
+		carpark.insertCar(defaultVehicleTypes.newVehicle("car", fmt.Sprintf("KA-01-CC-%04v", ii), "White"))
+		carpark.insertCar(defaultVehicleTypes.newVehicle("motorcycle", fmt.Sprintf("KA-01-MM-%04v", ii), "Black"))
+	}
+	for slotNo := 3; slotNo <= 60; slotNo += 3 {
+		carpark.removeCar(slotNo)
+	}
+
+	got, err := carpark.planCompaction(20)
+	if err != nil {
+		t.Fatalf("Carpark.planCompaction() error = %v", err)
+	}
+	if got.start != 3 || len(got.moves) != 13 {
+		t.Fatalf("Carpark.planCompaction() start = %v, moves = %v, want 3, 13 moves", got.start, len(got.moves))
+	}
+	moved := make(map[Vehicle]int)
+	for _, move := range got.moves {
+		moved[move.vehicle] = move.to
+	}
+	taken := make(map[int]bool)
+	for _, vehicle := range carpark.Map {
+		slotNo, ok := moved[vehicle]
+		if !ok {
+			slotNo = *vehicle.getSlot()
+		}
+		for slot := slotNo; slot < slotNo+vehicle.getSlotsNeeded(); slot++ {
+			if taken[slot] || slot > 60 || slot >= got.start && slot < got.start+20 {
+				t.Errorf("Carpark.planCompaction() leaves %v at slot %v, which is not free", *vehicle.getRegistration(), slotNo)
+			}
+			taken[slot] = true
+		}
+	}
+}
+
+func TestCarpark_applyCompaction(t *testing.T) {
+	dir, err := ioutil.TempDir("", "events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	fileName := filepath.Join(dir, "events.log")
+
+	carpark := &Carpark{clock: fixedClock(testTime)}
+	if carpark.events, err = openEventLog(fileName); err != nil {
+		t.Fatal(err)
+	}
+	defer carpark.events.file.Close()
+	scatter(carpark)
+	carpark.setQueue(queueFIFO)
+	if slotNo, _, err := carpark.insertCar(defaultVehicleTypes.newVehicle("bus", "KA-01-BB-0001", "Red")); err != nil || slotNo != 0 {
+		t.Fatalf("Carpark.insertCar() = %v, %v, want the vehicle queued", slotNo, err)
+	}
+
+	//The waiting bus is admitted into the slots opened
+	plan, err := carpark.applyCompaction(3)
+	if err != nil {
+		t.Fatalf("Carpark.applyCompaction() error = %v", err)
+	}
+	slotNo, err := carpark.getCarWithRegistrationNo("KA-01-MM-0003")
+	if err != nil || slotNo != 6 {
+		t.Errorf("Carpark.applyCompaction() moved KA-01-MM-0003 to slot %v, want 6", slotNo)
+	}
+	if len(plan.admitted) != 1 || *plan.admitted[0].getRegistration() != "KA-01-BB-0001" || *plan.admitted[0].getSlot() != 2 {
+		t.Errorf("Carpark.applyCompaction() admitted = %v, want KA-01-BB-0001 at slot 2", plan.admitted)
+	}
+
+	//Replaying the event log repeats the moves
+	replayed := &Carpark{}
+	if err := replayed.replay(fileName, time.Time{}); err != nil {
+		t.Fatalf("replay() error = %v", err)
+	}
+	compareCarpark(t, replayed, carpark)
+}
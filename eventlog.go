@@ -0,0 +1,262 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+//Types of events recorded in the event log
+const (
+	eventCreate         = "create"
+	eventPark           = "park"
+	eventLeave          = "leave"
+	eventCompact        = "compact"
+	eventZone           = "zone"
+	eventOverflow       = "overflow"
+	eventSetAttribute   = "set_attribute"
+	eventClearAttribute = "clear_attribute"
+	eventReserve        = "reserve"
+	eventQueue          = "queue"
+	eventEnqueue        = "enqueue"
+	eventResize         = "resize"
+	eventClose          = "close"
+	eventOpen           = "open"
+)
+
+//event records one change to the carpark
+type event struct {
+	Seq          int         `json:"seq"`                    //Position of the event in the log, starting from 1
+	Time         time.Time   `json:"time"`                   //Time at which the change happened
+	Type         string      `json:"type"`                   //One of create, park, leave, compact, zone, overflow, set_attribute, clear_attribute, reserve, queue, enqueue, resize, close, or open
+	Layout       []int       `json:"layout,omitempty"`       //Number of slots on each floor of a created carpark
+	Strategy     string      `json:"strategy,omitempty"`     //Allocation strategy of a created carpark
+	Vehicle      string      `json:"vehicle,omitempty"`      //Type of the parked vehicle
+	Registration string      `json:"registration,omitempty"` //Registration number of the parked vehicle
+	Colour       string      `json:"colour,omitempty"`       //Colour of the parked vehicle
+	Permits      []string    `json:"permits,omitempty"`      //Permits and needs of the parked vehicle
+	Slot         int         `json:"slot,omitempty"`         //Slot in which the vehicle parked or from which it left
+	Moves        []eventMove `json:"moves,omitempty"`        //Vehicles relocated by a compaction
+	Zone         string      `json:"zone,omitempty"`         //Type given to the slots from Slot to LastSlot
+	LastSlot     int         `json:"last_slot,omitempty"`    //Last slot given a type or attribute
+	Zones        []string    `json:"zones,omitempty"`        //Zones into which the vehicle type overflows
+	Attribute    string      `json:"attribute,omitempty"`    //Attribute given to or taken from the slots from Slot to LastSlot
+	Window       *window     `json:"window,omitempty"`       //Time window of a reservation
+	Queue        string      `json:"queue,omitempty"`        //Order of the waiting queue
+	Resize       int         `json:"resize,omitempty"`       //Number of slots added to the top floor, or removed when negative
+	Reason       string      `json:"reason,omitempty"`       //Reason the slot was closed
+}
+
+//window records the time window of a reservation
+type window struct {
+	From time.Time `json:"from"`
+	To   time.Time `json:"to"`
+}
+
+//eventMove records a vehicle relocated from one slot to another
+type eventMove struct {
+	From int `json:"from"`
+	To   int `json:"to"`
+}
+
+//eventLog appends events to a file, one JSON object per line
+type eventLog struct {
+	file *os.File
+	seq  int //Sequence number of the last event written
+}
+
+//openEventLog opens an event log for appending, continuing the sequence numbers of any events already in it
+func openEventLog(fileName string) (*eventLog, error) {
+	events, err := readEvents(fileName)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	file, err := os.OpenFile(fileName, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	eventLog := &eventLog{file: file}
+	if len(events) > 0 {
+		eventLog.seq = events[len(events)-1].Seq
+	}
+	return eventLog, nil
+}
+
+//append writes an event to the log, assigning its sequence number
+func (eventLog *eventLog) append(event *event) error {
+	event.Seq = eventLog.seq + 1
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	if _, err := eventLog.file.Write(append(data, '\n')); err != nil {
+		return err
+	}
+	if err := eventLog.file.Sync(); err != nil {
+		return err
+	}
+	eventLog.seq = event.Seq
+	return nil
+}
+
+//readEvents reads every event in a log file, in order
+func readEvents(fileName string) ([]*event, error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var events []*event
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		event := &event{}
+		if err := json.Unmarshal(scanner.Bytes(), event); err != nil {
+			return nil, fmt.Errorf("Invalid event log %v, line %v: %v", fileName, line, err)
+		}
+		events = append(events, event)
+	}
+	return events, scanner.Err()
+}
+
+//Rebuild an empty carpark from the events in a log file which happened no later than 'until', or from
+//every event when 'until' is zero, keeping the tariffs, vehicle types, and other configuration of the carpark
+func (carpark *Carpark) replay(fileName string, until time.Time) error {
+	events, err := readEvents(fileName)
+	if err != nil {
+		return err
+	}
+	for _, event := range events {
+		if !until.IsZero() && event.Time.After(until) {
+			break
+		}
+		if err := carpark.apply(event); err != nil {
+			return fmt.Errorf("Cannot replay event %v: %v", event.Seq, err)
+		}
+	}
+	carpark.mu.Lock()
+	defer carpark.mu.Unlock()
+	carpark.changed()
+	return nil
+}
+
+//parseTime reads a point in time given as RFC 3339 or as a local date and time
+func parseTime(value string) (time.Time, error) {
+	for _, layout := range []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02T15:04"} {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("Invalid time %q, expected a time such as 2006-01-02T15:04", value)
+}
+
+//Record an event in the carpark's event log, if it keeps one
+func (carpark *Carpark) record(event *event) error {
+	if carpark.events == nil {
+		return nil
+	}
+	return carpark.events.append(event)
+}
+
+//Apply a recorded event to the carpark
+func (carpark *Carpark) apply(event *event) error {
+	carpark.mu.Lock()
+	defer carpark.mu.Unlock()
+
+	switch err := carpark.initStatus(); {
+	case event.Type == eventCreate && err == nil:
+		return errAlreadyInitialized
+	case event.Type != eventCreate && err != nil:
+		return err
+	}
+
+	switch event.Type {
+	case eventCreate:
+		if err := validLayout(event.Layout); err != nil {
+			return err
+		}
+		strategy, err := parseStrategy(event.Strategy)
+		if err != nil {
+			return err
+		}
+		carpark.build(event.Layout, strategy)
+	case eventPark:
+		vehicle := carpark.types().newVehicle(event.Vehicle, event.Registration, event.Colour)
+		floor := carpark.floorOf(event.Slot)
+		if _, ok := carpark.Map[event.Slot]; vehicle == nil || floor == nil || ok {
+			return fmt.Errorf("cannot park %v %v at slot %v", event.Vehicle, event.Registration, event.Slot)
+		}
+		permits, err := parseAttributes(event.Permits, permitNames, errUnknownPermit)
+		if err != nil {
+			return err
+		}
+		*vehicle.getPermits() = permits
+		floor.occupy(event.Slot, vehicle.getSlotsNeeded())
+		carpark.place(vehicle, event.Slot, event.Time)
+		carpark.arrived(event.Registration)
+	case eventLeave:
+		if _, err := carpark.remove(event.Slot); err != nil {
+			return err
+		}
+	case eventCompact:
+		var moves []move
+		for _, eventMove := range event.Moves {
+			vehicle, ok := carpark.Map[eventMove.From]
+			if !ok || carpark.floorOf(eventMove.To) == nil {
+				return fmt.Errorf("cannot move slot %v to slot %v", eventMove.From, eventMove.To)
+			}
+			moves = append(moves, move{vehicle: vehicle, from: eventMove.From, to: eventMove.To})
+		}
+		carpark.relocate(moves)
+	case eventZone:
+		carpark.zone(event.Zone, event.Slot, event.LastSlot)
+	case eventOverflow:
+		carpark.overflowInto(event.Vehicle, event.Zones)
+	case eventSetAttribute, eventClearAttribute:
+		attribute, ok := attributeNames[event.Attribute]
+		if !ok {
+			return errUnknownAttribute
+		}
+		carpark.mark(attribute, event.Slot, event.LastSlot, event.Type == eventSetAttribute)
+	case eventQueue:
+		carpark.queueMode(event.Queue)
+	case eventEnqueue:
+		vehicle := carpark.types().newVehicle(event.Vehicle, event.Registration, event.Colour)
+		if vehicle == nil || carpark.queue == nil {
+			return fmt.Errorf("cannot queue %v %v", event.Vehicle, event.Registration)
+		}
+		permits, err := parseAttributes(event.Permits, permitNames, errUnknownPermit)
+		if err != nil {
+			return err
+		}
+		*vehicle.getPermits() = permits
+		*vehicle.getArrival() = event.Time
+		carpark.queue.push(vehicle)
+	case eventResize:
+		if top := carpark.floors[len(carpark.floors)-1]; top.maxSlot+event.Resize < top.firstSlot {
+			return errEmptyFloor
+		}
+		carpark.resize(event.Resize)
+	case eventClose, eventOpen:
+		if carpark.floorOf(event.Slot) == nil {
+			return errSlotNotFound
+		}
+		if event.Type == eventClose {
+			carpark.outOfService(event.Slot, event.Reason)
+		} else {
+			carpark.inService(event.Slot)
+		}
+	case eventReserve:
+		vehicle := carpark.types().newVehicle(event.Vehicle, event.Registration, "")
+		if vehicle == nil || event.Window == nil || carpark.floorOf(event.Slot) == nil {
+			return fmt.Errorf("cannot reserve slot %v for %v %v", event.Slot, event.Vehicle, event.Registration)
+		}
+		carpark.hold(&reservation{registration: event.Registration, vehicleType: event.Vehicle, slot: event.Slot,
+			slots: vehicle.getSlotsNeeded(), from: event.Window.From, to: event.Window.To})
+	default:
+		return fmt.Errorf("unknown event type %q", event.Type)
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func Test_replay(t *testing.T) {
+	dir, err := ioutil.TempDir("", "events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	fileName := filepath.Join(dir, "events.log")
+
+	//Operate a carpark whose clock advances by an hour on every change
+	now := testTime
+	carpark := &Carpark{clock: func() time.Time { return now }}
+	if carpark.events, err = openEventLog(fileName); err != nil {
+		t.Fatal(err)
+	}
+	operate := func(f func()) {
+		f()
+		now = now.Add(time.Hour)
+	}
+	operate(func() { carpark.init(nil, 3, 3) })                                                            //09:00
+	operate(func() { carpark.insertCar(values().vehicle0) })                                               //10:00 slot 1
+	operate(func() { carpark.insertCar(defaultVehicleTypes.newVehicle("bus", "KA-01-BB-0001", "Black")) }) //11:00 slots 4-6
+	operate(func() { carpark.removeCar(1) })                                                               //12:00
+	operate(func() { carpark.insertCar(defaultVehicleTypes.newVehicle("car", "KA-01-HH-9999", "White")) }) //13:00 slots 1-2
+	carpark.events.file.Close()
+
+	tests := []struct {
+		name         string
+		until        time.Time
+		wantVehicles []string
+	}{
+		{name: "Before the carpark was created", until: testTime.Add(-time.Minute), wantVehicles: nil},
+		{name: "Empty carpark", until: testTime, wantVehicles: nil},
+		{name: "Replay to 10:30", until: testTime.Add(90 * time.Minute), wantVehicles: []string{"Motorcycle"}},
+		{name: "Replay to 11:00", until: testTime.Add(2 * time.Hour), wantVehicles: []string{"Motorcycle", "Bus"}},
+		{name: "Replay to 12:00", until: testTime.Add(3 * time.Hour), wantVehicles: []string{"Bus"}},
+		{name: "Replay everything", until: time.Time{}, wantVehicles: []string{"Car", "Bus"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := &Carpark{}
+			if err := got.replay(fileName, tt.until); err != nil {
+				t.Fatalf("replay() error = %v", err)
+			}
+			var gotVehicles []string
+			for _, vehicle := range got.getStatus() {
+				gotVehicles = append(gotVehicles, vehicle.getType())
+			}
+			if !reflect.DeepEqual(gotVehicles, tt.wantVehicles) {
+				t.Errorf("replay() vehicles = %v, want %v", gotVehicles, tt.wantVehicles)
+			}
+			if tt.until.IsZero() {
+				compareCarpark(t, got, carpark)
+			}
+		})
+	}
+
+	//Replaying keeps the tariffs of the carpark
+	configured := &Carpark{
+		clock:   func() time.Time { return now },
+		tariffs: &tariffPlan{Tariffs: map[string]tariff{"Motorcycle": {}, "Car": {FirstHour: 900}, "Bus": {}}},
+	}
+	if err := configured.replay(fileName, time.Time{}); err != nil {
+		t.Fatalf("replay() error = %v", err)
+	}
+	if receipt, err := configured.quote("KA-01-HH-9999"); err != nil || receipt.fee != 900 {
+		t.Errorf("Carpark.quote() after replay = %v, %v, want a fee of 900", receipt, err)
+	}
+
+	//Reopening the log continues the sequence numbers
+	events, err := openEventLog(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer events.file.Close()
+	if events.seq != 5 {
+		t.Errorf("openEventLog() seq = %v, want 5", events.seq)
+	}
+}
+
+func TestCarpark_applyInvalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		event event
+	}{
+		{name: "Carpark without floors", event: event{Type: eventCreate, Strategy: "first_fit"}},
+		{name: "Floor without slots", event: event{Type: eventCreate, Layout: []int{3, 0}, Strategy: "first_fit"}},
+		{name: "Resize before the carpark was created", event: event{Type: eventResize, Resize: 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			carpark := &Carpark{}
+			if err := carpark.apply(&tt.event); err == nil {
+				t.Errorf("Carpark.apply() error = nil, want error")
+			}
+			if carpark.initStatus() == nil {
+				t.Errorf("Carpark.apply() created the carpark")
+			}
+		})
+	}
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"container/list"
+	"sortedlist"
+)
+
+//floor represents one level of the carpark with its own range of slots
+type floor struct {
+	level       int            //Floor number, starting from 1 at the lowest floor
+	firstSlot   int            //Lowest slot number on the floor
+	highestSlot int            //Highest slot number filled on the floor throughout carpark operation
+	maxSlot     int            //Highest slot number available on the floor
+	emptySlots  *list.List     //List containing sorted empty slots in ascending order
+	closed      map[int]string //Reason each slot out of service was closed
+}
+
+//newFloor creates an empty floor holding 'slots' slots numbered from 'firstSlot'
+func newFloor(level int, firstSlot int, slots int) *floor {
+	return &floor{
+		level:       level,
+		firstSlot:   firstSlot,
+		highestSlot: firstSlot - 1,
+		maxSlot:     firstSlot + slots - 1,
+		emptySlots:  list.New(),
+	}
+}
+
+//candidates lists every position on the floor where 'slotsNeeded' consecutive free slots start,
+//first within the empty slots and then beyond the highest slot filled, treating empty slots
+//which reach up to the highest slot as part of the free slots beyond it, and closed slots as filled
+func (floor *floor) candidates(slotsNeeded int) []candidate {
+	var candidates []candidate
+	addGap := func(gapStart int, gapSize int) {
+		runStart := gapStart
+		for slot := gapStart; slot <= gapStart+gapSize; slot++ {
+			if _, closed := floor.closed[slot]; !closed && slot < gapStart+gapSize {
+				continue
+			}
+			for position := runStart; position+slotsNeeded <= slot; position++ {
+				candidates = append(candidates, candidate{slot: position, slots: slotsNeeded, gapStart: runStart, gapSize: slot - runStart, floor: floor})
+			}
+			runStart = slot + 1
+		}
+	}
+	gapStart, gapSize := 0, 0
+	for e := floor.emptySlots.Front(); e != nil; e = e.Next() {
+		if slot := e.Value.(int); gapSize > 0 && slot == gapStart+gapSize {
+			gapSize++
+		} else {
+			addGap(gapStart, gapSize)
+			gapStart, gapSize = slot, 1
+		}
+	}
+	if gapSize > 0 && gapStart+gapSize-1 == floor.highestSlot {
+		addGap(gapStart, gapSize+floor.maxSlot-floor.highestSlot)
+	} else {
+		addGap(gapStart, gapSize)
+		addGap(floor.highestSlot+1, floor.maxSlot-floor.highestSlot)
+	}
+	return candidates
+}
+
+//release returns 'slotsNeeded' slots starting at 'slotNo' to the floor's empty slots
+func (floor *floor) release(slotNo int, slotsNeeded int) {
+	sortedlist.Insert(floor.emptySlots, floor.emptySlots.Back(), slotNo, slotsNeeded)
+}
+
+//contains checks whether the slot number lies on the floor
+func (floor *floor) contains(slotNo int) bool {
+	return slotNo >= floor.firstSlot && slotNo <= floor.maxSlot
+}
+
+//size returns the number of slots on the floor
+func (floor *floor) size() int {
+	return floor.maxSlot - floor.firstSlot + 1
+}
+
+//occupy takes 'slotsNeeded' slots starting at 'slotNo' out of the floor's free slots
+func (floor *floor) occupy(slotNo int, slotsNeeded int) {
+	for slot := slotNo; slot < slotNo+slotsNeeded; slot++ {
+		if slot <= floor.highestSlot {
+			for e := floor.emptySlots.Front(); e != nil; e = e.Next() {
+				if e.Value.(int) == slot {
+					floor.emptySlots.Remove(e)
+					break
+				}
+			}
+			continue
+		}
+		for gap := floor.highestSlot + 1; gap < slot; gap++ { //Slots skipped over become empty slots
+			floor.emptySlots.PushBack(gap)
+		}
+		floor.highestSlot = slot
+	}
+}
+
+//grow adds 'slots' slots to the top of the floor
+func (floor *floor) grow(slots int) {
+	floor.maxSlot += slots
+}
+
+//shrink removes the free or closed slots above 'maxSlot' from the top of the floor, dropping trailing empty slots
+//so that the highest slot filled stays occupied
+func (floor *floor) shrink(maxSlot int) {
+	floor.maxSlot = maxSlot
+	for slotNo := range floor.closed {
+		if slotNo > maxSlot {
+			delete(floor.closed, slotNo)
+		}
+	}
+	for e := floor.emptySlots.Back(); e != nil && e.Value.(int) > maxSlot; e = floor.emptySlots.Back() {
+		floor.emptySlots.Remove(e)
+	}
+	if floor.highestSlot > maxSlot {
+		floor.highestSlot = maxSlot
+	}
+	for e := floor.emptySlots.Back(); e != nil && e.Value.(int) == floor.highestSlot; e = floor.emptySlots.Back() {
+		floor.emptySlots.Remove(e)
+		floor.highestSlot--
+	}
+}
@@ -2,162 +2,269 @@ package main
 
 import (
 	"bufio"
+	"errors"
+	"flag"
 	"fmt"
 	"io"
 	"log"
 	"os"
-	"pretty"
-	"runtime"
 	"strconv"
-	"strings"
-	"text/tabwriter"
+	"time"
 )
 
 var inputInteractive io.Reader = os.Stdin
 var outStream io.Writer = os.Stdout
+var osExit = os.Exit
+
+//errUnknownCommand reports an input line which is not a command
+var errUnknownCommand = errors.New("Unknown input command")
+
+//defaultAddress is the network address served when none is given to the serve mode
+const defaultAddress = ":8080"
 
 func main() {
 
-	//Input file or interactive mode
-	ii := len(os.Args)
+	//Command line options
+	flags := flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	tariffFile := flags.String("tariffs", "", "JSON file of parking tariffs for each vehicle type")
+	vehicleFile := flags.String("vehicles", "", "JSON file of vehicle types, with the slots, zones, and tariff of each")
+	format := flags.String("format", formatText, "Format of command results: text, json, or csv")
+	plates := flags.String("plates", "any", "Format of registration numbers accepted: any, india, singapore, or uk")
+	stateFile := flags.String("state", "", "File to restore the carpark from at startup and to save it to")
+	eventFile := flags.String("events", "", "File to append every park and leave event to")
+	strict := flags.Bool("strict", false, "Stop at the first command which fails, exiting with status 1")
+	summarize := flags.Bool("summary", false, "Report the commands which succeeded and failed once the input ends, exiting with status 1 if any failed")
+	historyFile := flags.String("history", defaultHistoryFile(), "File keeping the commands typed at a Linux or macOS terminal, or \"\" to keep none")
+	saveInterval := flags.Duration("save-interval", 0, "Time between saves of the state file, or 0 to save after every change")
+	flags.Parse(os.Args[1:])
+
+	//Create a carpark
+	var err error
+	if outFormat, err = parseFormat(*format); err != nil {
+		log.Fatal(err)
+	}
+	var carpark = &Carpark{}
+	if carpark.plates, err = parsePlates(*plates); err != nil {
+		log.Fatal(err)
+	}
+	if *tariffFile != "" {
+		tariffs, err := loadTariffs(*tariffFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		carpark.tariffs = tariffs
+	}
+	if *vehicleFile != "" {
+		registry, err := loadVehicleTypes(*vehicleFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		carpark.vehicleTypes = registry
+	}
+	tariffs := carpark.tariffs
+	if tariffs == nil {
+		tariffs = defaultTariffs
+	}
+	if err := carpark.types().priced(tariffs); err != nil {
+		log.Fatal(err)
+	}
+	if *stateFile != "" {
+		if err := carpark.persist(*stateFile, *saveInterval); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if *eventFile != "" {
+		events, err := openEventLog(*eventFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		carpark.events = events
+	}
+
+	//Server, replay, input file, or interactive mode
+	ii := flags.NArg()
 	var scanner *bufio.Scanner
+	closeConsole := func() {}
 	switch {
-	case ii > 2:
+	case ii >= 2 && flags.Arg(0) == "replay": //Rebuild the carpark from an event log and query it interactively
+		var until time.Time
+		if ii == 3 {
+			var err error
+			if until, err = parseTime(flags.Arg(2)); err != nil {
+				log.Fatal(err)
+			}
+		} else if ii > 3 {
+			log.Fatal("Unknown command line input")
+		}
+		if err := carpark.replay(flags.Arg(1), until); err != nil {
+			log.Fatal(err)
+		}
+		scanner, closeConsole = openConsole(carpark, *historyFile)
+	case ii >= 1 && flags.Arg(0) == "serve":
+		address := defaultAddress
+		if ii == 2 {
+			address = flags.Arg(1)
+		} else if ii > 2 {
+			log.Fatal("Unknown command line input")
+		}
+		log.Printf("Serving carpark on %v", address)
+		if err := serveCarpark(carpark, address); err != nil {
+			log.Fatal(err)
+		}
+		return
+	case ii > 1:
 		log.Fatal("Unknown command line input")
-	case ii == 2:
-		inputFile, err := os.Open(os.Args[1])
+	case ii == 1:
+		inputFile, err := os.Open(flags.Arg(0))
 		if err != nil {
 			panic(err)
 		}
 		defer inputFile.Close()
 		scanner = bufio.NewScanner(inputFile)
 	default:
-		scanner = bufio.NewScanner(inputInteractive)
+		scanner, closeConsole = openConsole(carpark, *historyFile)
 	}
 
-	//Create a carpark
-	var carpark = &Carpark{}
-
-	//Operate the carpark
-	operateCarpark(carpark, scanner)
+	//Operate the carpark, restoring the terminal before exiting and if a command panics
+	defer closeConsole()
+	summary := operateCarpark(carpark, scanner, *strict)
+	closeConsole()
+	if err := carpark.close(); err != nil {
+		log.Fatal(err)
+	}
+	if *summarize {
+		if err := summary.report().write(outStream, outFormat); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if (*strict || *summarize) && summary.failed() {
+		osExit(1)
+	}
 }
 
-//operateCarpark reads input queries from console or text file and executes the command
-func operateCarpark(carpark *Carpark, scanner *bufio.Scanner) {
-	newlineStr := getNewlineStr()
+//operateCarpark reads input queries from console or text file and executes the command, stopping at the
+//first command which fails when 'strict' is set, and returns the outcome of the commands
+func operateCarpark(carpark *Carpark, scanner *bufio.Scanner, strict bool) *summary {
+	summary := &summary{}
+	commands := newCommandReader(scanner)
 	exit := false
-	for !exit && scanner.Scan() {
-		input := scanner.Text()
-		input = strings.TrimRight(input, newlineStr)
-		s := parse(input)
+	for !exit {
+		s, err := commands.next()
+		if err == io.EOF {
+			break
+		}
+		out := &report{}
+		if len(s) > 0 {
+			out.command = s[0]
+		}
 
 		switch {
-		case s[0] == "create_parking_lot" && len(s) == 2: //Initialize carpark
-			maxSlot, err := strconv.Atoi(s[1])
-			if checkError(err) {
-				break
-			}
-			err = carpark.init(maxSlot)
-			if !checkError(err) {
-				fmt.Fprintf(outStream, "Created a parking lot with %v slots\n", maxSlot)
-			}
-
-		case s[0] == "park" && len(s) == 4: //Park a new vehicle
-			var vehicle Vehicle
-			switch s[3] {
-			case "car":
-				car := NewCar()
-				car.registration = s[1]
-				car.colour = s[2]
-				vehicle = car
-			case "motorcycle":
-				motorcycle := NewMotorcycle()
-				motorcycle.registration = s[1]
-				motorcycle.colour = s[2]
-				vehicle = motorcycle
-			case "bus":
-				bus := NewBus()
-				bus.registration = s[1]
-				bus.colour = s[2]
-				vehicle = bus
-			}
-			slotNo, err := carpark.insertCar(vehicle)
-			if !checkError(err) {
-				fmt.Fprintf(outStream, "Allocated slot number: %v\n", slotNo)
-			}
-
-		case s[0] == "leave" && len(s) == 2: //Remove a parked vehicle
-			slotNo, err := strconv.Atoi(s[1])
-			if checkError(err) {
-				break
-			}
-			err = carpark.removeCar(slotNo)
-			if !checkError(err) {
-				fmt.Fprintf(outStream, "Slot number %v is free\n", slotNo)
-			}
-
-		case s[0] == "registration_numbers_for_cars_with_colour" && len(s) == 2: //Return registration numbers with given vehicle colour
-			_, registration, err := carpark.getCarsWithColour(s[1])
-			if checkError(err) {
-				break
-			}
-			err = pretty.Printer(registration, outStream)
-			if err != nil {
-				panic(err.Error())
-			}
-
-		case s[0] == "slot_numbers_for_cars_with_colour" && len(s) == 2: //Return slot numbers with given vehicle colour
-			slots, _, err := carpark.getCarsWithColour(s[1])
-			if checkError(err) {
-				break
-			}
-			err = pretty.Printer(slots, outStream)
-			if err != nil {
-				panic(err.Error())
-			}
-
-		case s[0] == "slot_number_for_registration_number" && len(s) == 2: //Return slot numbers with given vehicle registration number
-			slotNo, err := carpark.getCarWithRegistrationNo(s[1])
-			if !checkError(err) {
-				fmt.Fprintln(outStream, slotNo)
+		case err != nil: //Report a command which could not be read, and stop when the input itself failed
+			out.check(err)
+			_, syntax := err.(*syntaxError)
+			exit = !syntax
+		default:
+			err = consoleCommands.run(carpark, s, commands.errorAt, out)
+			exit = err == errExit
+			if !exit {
+				out.check(err)
 			}
+		}
+		if err := out.write(outStream, outFormat); err != nil {
+			panic(err.Error())
+		}
+		if err != errExit {
+			summary.add(commands.start, s, err)
+			exit = exit || strict && summary.failed()
+		}
+	}
+	return summary
+}
 
-		case s[0] == "status" && len(s) == 1: //Retrieve vehicles parked in carpark
-			vehicles := carpark.getStatus()
-			var w = tabwriter.NewWriter(outStream, 0, 0, 4, ' ', 0)
-			fmt.Fprintln(w, "Slot No.\tRegistration No\tColour\tType")
-			for _, vehicle := range vehicles {
-				s := fmt.Sprintf("%v\t%s\t%s\t%s", *vehicle.getSlot(), *vehicle.getRegistration(), *vehicle.getColour(), vehicle.getType())
-				fmt.Fprintln(w, s)
-			}
-			w.Flush()
+//parseLayout converts the slot count of each floor into integers, followed by an optional allocation strategy
+func parseLayout(args []string) ([]int, allocator, error) {
+	var strategy allocator
+	if _, err := strconv.Atoi(args[len(args)-1]); err != nil && len(args) > 1 {
+		if strategy, err = parseStrategy(args[len(args)-1]); err != nil {
+			return nil, nil, err
+		}
+		args = args[:len(args)-1]
+	}
+	var layout []int
+	for _, arg := range args {
+		slots, err := strconv.Atoi(arg)
+		if err != nil {
+			return nil, nil, err
+		}
+		layout = append(layout, slots)
+	}
+	return layout, strategy, nil
+}
 
-		case s[0] == "exit" && len(s) == 1: //End carpark operation
-			exit = true
+//sum adds up a sequence of integers
+func sum(values []int) int {
+	total := 0
+	for _, value := range values {
+		total += value
+	}
+	return total
+}
 
-		default: //Default option
-			fmt.Fprintln(outStream, "Unknown input command")
+//slotLabels formats slot numbers for output, naming the floor of each slot in a multi-level carpark
+func slotLabels(carpark *Carpark, slots ...int) []string {
+	var labels []string
+	for _, slotNo := range slots {
+		if carpark.multiLevel() {
+			labels = append(labels, fmt.Sprintf("%v (floor %v)", slotNo, carpark.getLevel(slotNo)))
+		} else {
+			labels = append(labels, strconv.Itoa(slotNo))
 		}
 	}
+	return labels
 }
 
-//getNewlineStr identifies operating system and returns newline character used
-func getNewlineStr() string {
-	if runtime.GOOS == "windows" {
-		return "\r\n"
+//printAdmitted reports the waiting vehicles parked in freed or added slots
+func printAdmitted(out *report, carpark *Carpark, admitted []Vehicle) {
+	var slots []map[string]interface{}
+	for _, vehicle := range admitted {
+		out.printf("Allocated slot number: %v to %v from the waiting queue", slotLabels(carpark, *vehicle.getSlot())[0], *vehicle.getRegistration())
+		slots = append(slots, map[string]interface{}{"registration": *vehicle.getRegistration(), "slot": *vehicle.getSlot()})
+	}
+	if len(slots) > 0 {
+		out.set("admitted", slots)
 	}
-	return "\n"
 }
 
-func parse(input string) []string {
-	s := strings.Split(input, " ")
-	return s
+//printReceipt reports the parking duration and fee of a vehicle
+func printReceipt(out *report, receipt *receipt) {
+	out.println(receipt)
+	out.set("registration", *receipt.vehicle.getRegistration())
+	out.set("slots", receipt.slots)
+	out.set("duration", formatDuration(receipt.duration()))
+	out.set("fee", formatFee(receipt.fee))
 }
 
-func checkError(err error) bool {
-	if err != nil {
-		fmt.Fprintln(outStream, err.Error())
-		return true
+//printCompaction reports the vehicle moves of a compaction plan and the run of slots they free
+func printCompaction(out *report, carpark *Carpark, plan *compactionPlan, applied bool) {
+	run := slotLabels(carpark, plan.start, plan.start+plan.slots-1)
+	moves := []map[string]interface{}{}
+	for _, move := range plan.moves {
+		moves = append(moves, map[string]interface{}{"registration": *move.vehicle.getRegistration(), "from": move.from, "to": move.to})
+	}
+	out.set("first", plan.start)
+	out.set("last", plan.start+plan.slots-1)
+	out.set("moves", moves)
+	if len(plan.moves) == 0 {
+		out.printf("Slots %v to %v are already free", run[0], run[1])
+		return
+	}
+	verb, result := "Move", "would be free"
+	if applied {
+		verb, result = "Moved", "are free"
+	}
+	for _, move := range plan.moves {
+		labels := slotLabels(carpark, move.from, move.to)
+		out.printf("%v %v from slot %v to slot %v", verb, *move.vehicle.getRegistration(), labels[0], labels[1])
 	}
-	return false
+	out.printf("Slots %v to %v %v", run[0], run[1], result)
 }
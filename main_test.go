@@ -1,9 +1,11 @@
 package main
 
 import (
+	"bufio"
 	"bytes"
 	"log"
 	"os"
+	"strings"
 	"testing"
 )
 
@@ -16,6 +18,7 @@ Allocated slot number: 4
 Allocated slot number: 5
 Allocated slot number: 6
 Slot number 4 is free
+Duration: 0h00m, Fee: 0.00
 Slot No.    Registration No    Colour    Type
 1           KA-01-HH-1234      White     Motorcycle
 2           KA-01-HH-9999      White     Motorcycle
@@ -30,7 +33,7 @@ KA-01-HH-1234, KA-01-HH-9999, KA-01-P-333
 Not found
 Not found
 Not found
-Unknown input command
+Line 18, token 1: unknown command "parked", try help
 `
 	return out
 }
@@ -40,10 +43,12 @@ func Test_main(t *testing.T) {
 	oldArgs := os.Args
 	oldInputInteractive := inputInteractive
 	oldOutStream := outStream
+	oldOutFormat := outFormat
 	defer func() {
 		os.Args = oldArgs
 		inputInteractive = oldInputInteractive
 		outStream = oldOutStream
+		outFormat = oldOutFormat
 	}()
 
 	//Setup redirection for interactive inputs
@@ -83,3 +88,132 @@ func Test_main(t *testing.T) {
 		gotBuf.Reset()
 	}
 }
+
+func Test_operateCarpark(t *testing.T) {
+	//Save old settings before rewriting settings
+	oldOutStream := outStream
+	defer func() { outStream = oldOutStream }()
+
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{name: "Multi-level carpark",
+			input: `create_parking_lot 2 2
+park KA-01-HH-1234 White motorcycle
+park KA-01-HH-9999 White car
+park KA-01-BB-0001 Black motorcycle
+status
+slot_numbers_for_cars_with_colour White
+slot_number_for_registration_number KA-01-BB-0001
+leave_by_registration KA-01-HH-9999
+leave_by_registration KA-01-HH-1234
+leave_by_registration KA-01-HH-1234
+leave 2
+`,
+			want: `Created a parking lot with 4 slots on 2 floors
+Allocated slot number: 1 on floor 1
+Allocated slot number: 3 on floor 2
+Allocated slot number: 2 on floor 1
+Floor    Slot No.    Registration No    Colour    Type
+1        1           KA-01-HH-1234      White     Motorcycle
+1        2           KA-01-BB-0001      Black     Motorcycle
+2        3           KA-01-HH-9999      White     Car
+1 (floor 1), 3 (floor 2)
+2 (floor 1)
+Slot numbers 3 (floor 2), 4 (floor 2) are free
+Duration: 0h00m, Fee: 0.00
+Slot number 1 (floor 1) is free
+Duration: 0h00m, Fee: 0.00
+Not found
+Slot number 2 (floor 1) is free
+Duration: 0h00m, Fee: 0.00
+`,
+		},
+		{name: "Closed slots",
+			input: `create_parking_lot 5
+close_slot 2 broken barrier
+park KA-01-HH-9999 White car
+park KA-01-HH-1234 White motorcycle
+close_slot 1
+close_slot 5
+status
+open_slot 2
+park KA-01-BB-0001 Black motorcycle
+`,
+			want: `Created a parking lot with 5 slots
+Slot number 2 is closed
+Allocated slot number: 3
+Allocated slot number: 1
+Sorry, a vehicle is parked in that slot
+Slot number 5 is closed
+Slot No.    Registration No    Colour    Type
+1           KA-01-HH-1234      White     Motorcycle
+2                                        Closed: broken barrier
+3           KA-01-HH-9999      White     Car
+5                                        Closed: maintenance
+Slot number 2 is open
+Allocated slot number: 2
+`,
+		},
+		{name: "Quotes, comments and continued lines",
+			input: `# Small carpark
+Create_Parking_Lot  4
+
+park KA-01-HH-1234 "Dark Blue" \
+	motorcycle # first arrival
+registration_numbers_for_cars_with_colour 'Dark Blue'
+leave one
+park KA-01-HH-9999 "White car
+`,
+			want: `Created a parking lot with 4 slots
+Allocated slot number: 1
+KA-01-HH-1234
+Line 7, token 2: "one" is not a number
+Line 8, token 3: unterminated quote
+`,
+		},
+		{name: "Waiting queue",
+			input: `create_parking_lot 2
+park KA-01-HH-9999 White car
+queue fifo
+park KA-01-HH-7777 Red car
+leave 1
+`,
+			want: `Created a parking lot with 2 slots
+Allocated slot number: 1
+Waiting queue is fifo
+Sorry, parking lot is full, vehicle added to the waiting queue
+Slot number 1 is free
+Duration: 0h00m, Fee: 0.00
+Allocated slot number: 1 to KA-01-HH-7777 from the waiting queue
+`,
+		},
+		{name: "Help and argument errors",
+			input: `help leave
+help parked
+create_parking_lot 4
+leave
+leave 1 2
+`,
+			want: `Usage: leave <slot>
+Remove the vehicle parked at a slot and charge it
+Unknown input command
+Created a parking lot with 4 slots
+Line 4, token 2: missing <slot>, usage: leave <slot>
+Line 5, token 3: unexpected argument "2", usage: leave <slot>
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotBuf bytes.Buffer
+			outStream = &gotBuf
+			operateCarpark(&Carpark{}, bufio.NewScanner(strings.NewReader(tt.input)), false)
+			if gotBuf.String() != tt.want {
+				t.Errorf("operateCarpark() = %v, want = %v", gotBuf.String(), tt.want)
+			}
+		})
+	}
+}
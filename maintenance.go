@@ -0,0 +1,110 @@
+package main
+
+import (
+	"errors"
+)
+
+//defaultClosure is the reason given for closing a slot when none is stated
+const defaultClosure = "maintenance"
+
+//Errors reported when closing or opening slots
+var (
+	errSlotOccupied = errors.New("Sorry, a vehicle is parked in that slot")
+	errSlotNotFound = errors.New("Slot non-existent in carpark")
+	errSlotOpen     = errors.New("Slot is not closed")
+)
+
+//Take a free slot out of service for a reason, or update the reason of a slot already closed
+func (carpark *Carpark) closeSlot(slotNo int, reason string) error {
+	carpark.mu.Lock()
+	defer carpark.mu.Unlock()
+
+	if err := carpark.initStatus(); err != nil {
+		return err
+	}
+	if carpark.floorOf(slotNo) == nil {
+		return errSlotNotFound
+	}
+	if !carpark.freeSlots()[slotNo] {
+		if _, closed := carpark.floorOf(slotNo).closed[slotNo]; !closed {
+			return errSlotOccupied
+		}
+	}
+	if reason == "" {
+		reason = defaultClosure
+	}
+	if err := carpark.record(&event{Type: eventClose, Time: carpark.now(), Slot: slotNo, Reason: reason}); err != nil {
+		return err
+	}
+	carpark.outOfService(slotNo, reason)
+	carpark.changed()
+	return nil
+}
+
+//Return a closed slot to service, admit waiting vehicles into it, and return the vehicles admitted
+func (carpark *Carpark) openSlot(slotNo int) ([]Vehicle, error) {
+	carpark.mu.Lock()
+	defer carpark.mu.Unlock()
+
+	if err := carpark.initStatus(); err != nil {
+		return nil, err
+	}
+	floor := carpark.floorOf(slotNo)
+	if floor == nil {
+		return nil, errSlotNotFound
+	}
+	if _, closed := floor.closed[slotNo]; !closed {
+		return nil, errSlotOpen
+	}
+	if err := carpark.record(&event{Type: eventOpen, Time: carpark.now(), Slot: slotNo}); err != nil {
+		return nil, err
+	}
+	carpark.inService(slotNo)
+	carpark.changed()
+	return carpark.admit(), nil
+}
+
+//Return the reason each closed slot was closed
+func (carpark *Carpark) getClosed() map[int]string {
+	carpark.mu.Lock()
+	defer carpark.mu.Unlock()
+
+	closed := make(map[int]string)
+	for _, floor := range carpark.floors {
+		for slotNo, reason := range floor.closed {
+			closed[slotNo] = reason
+		}
+	}
+	return closed
+}
+
+//The following helpers expect the caller to hold the carpark lock
+
+//Take a slot out of service for a reason
+func (carpark *Carpark) outOfService(slotNo int, reason string) {
+	floor := carpark.floorOf(slotNo)
+	if floor.closed == nil {
+		floor.closed = make(map[int]string)
+	}
+	floor.closed[slotNo] = reason
+}
+
+//Return a slot to service
+func (carpark *Carpark) inService(slotNo int) {
+	floor := carpark.floorOf(slotNo)
+	if delete(floor.closed, slotNo); len(floor.closed) == 0 {
+		floor.closed = nil
+	}
+}
+
+//Check whether any of 'slots' slots from 'slotNo' are closed
+func (carpark *Carpark) closedIn(slotNo int, slots int) bool {
+	for ii := 0; ii < slots; ii++ {
+		if floor := carpark.floorOf(slotNo + ii); floor != nil {
+			if _, closed := floor.closed[slotNo+ii]; closed {
+				return true
+			}
+		}
+	}
+	return false
+}
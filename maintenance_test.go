@@ -0,0 +1,72 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_floor_candidatesWithClosedSlots(t *testing.T) {
+	tests := []struct {
+		name        string
+		closed      []int
+		slotsNeeded int
+		want        []int
+	}{
+		{name: "No closed slots", slotsNeeded: 2, want: []int{2, 3, 6, 9, 10, 11}},
+		{name: "Closed empty slot splits its gap", closed: []int{3}, slotsNeeded: 2, want: []int{6, 9, 10, 11}},
+		{name: "Closed slot beyond the highest slot filled", closed: []int{10}, slotsNeeded: 2, want: []int{2, 3, 6, 11}},
+		{name: "Closed slots skipped for a single slot", closed: []int{2, 9, 12}, slotsNeeded: 1, want: []int{3, 4, 6, 7, 10, 11}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			floor := fragmentedFloor()[0]
+			for _, slotNo := range tt.closed {
+				if floor.closed == nil {
+					floor.closed = make(map[int]string)
+				}
+				floor.closed[slotNo] = defaultClosure
+			}
+			var got []int
+			for _, candidate := range floor.candidates(tt.slotsNeeded) {
+				got = append(got, candidate.slot)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("floor.candidates() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCarpark_closeSlot(t *testing.T) {
+	carpark := &Carpark{}
+	carpark.init(nil, 3)
+	carpark.insertCar(defaultVehicleTypes.newVehicle("motorcycle", "KA-01-MM-0001", "Black"))
+
+	if err := carpark.closeSlot(1, ""); err != errSlotOccupied {
+		t.Errorf("Carpark.closeSlot() error = %v, want %v", err, errSlotOccupied)
+	}
+	if err := carpark.closeSlot(4, ""); err != errSlotNotFound {
+		t.Errorf("Carpark.closeSlot() error = %v, want %v", err, errSlotNotFound)
+	}
+	if err := carpark.closeSlot(2, "cleaning"); err != nil {
+		t.Fatalf("Carpark.closeSlot() error = %v", err)
+	}
+	if err := carpark.closeSlot(2, "repairs"); err != nil {
+		t.Fatalf("Carpark.closeSlot() error = %v", err)
+	}
+	if got, want := carpark.getClosed(), map[int]string{2: "repairs"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Carpark.getClosed() = %v, want %v", got, want)
+	}
+	if got, _, _ := carpark.insertCar(defaultVehicleTypes.newVehicle("motorcycle", "KA-01-MM-0002", "Black")); got != 3 {
+		t.Errorf("Carpark.insertCar() = %v, want 3", got)
+	}
+	if _, err := carpark.openSlot(3); err != errSlotOpen {
+		t.Errorf("Carpark.openSlot() error = %v, want %v", err, errSlotOpen)
+	}
+	if _, err := carpark.openSlot(2); err != nil {
+		t.Fatalf("Carpark.openSlot() error = %v", err)
+	}
+	if got := carpark.floors[0].closed; got != nil {
+		t.Errorf("Carpark.openSlot() closed = %v, want none", got)
+	}
+}
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"pretty"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+//Formats in which the result of each command is written
+const (
+	formatText = "text" //Lines of text and aligned tables for reading
+	formatJSON = "json" //One JSON object per command
+	formatCSV  = "csv"  //Comma separated records
+)
+
+//errUnknownFormat reports an output format which does not exist
+var errUnknownFormat = errors.New("Unknown output format, expected text, json, or csv")
+
+//outFormat is the format in which command results are written to outStream
+var outFormat = formatText
+
+//parseFormat checks the name of an output format
+func parseFormat(name string) (string, error) {
+	switch name = strings.ToLower(name); name {
+	case formatText, formatJSON, formatCSV:
+		return name, nil
+	}
+	return "", errUnknownFormat
+}
+
+//report collects the result of a command until it is written
+type report struct {
+	command  string                 //Name of the command
+	messages []string               //Lines of text reporting the result
+	result   map[string]interface{} //Values of the result keyed by name, written in the structured formats
+	err      error                  //Error the command failed with
+	column   string                 //Heading of the list of values
+	values   []string               //List of values returned
+	headers  []string               //Headings of the table returned
+	rows     [][]string             //Rows of the table returned
+}
+
+//printf adds a line of text to the report
+func (report *report) printf(format string, args ...interface{}) {
+	report.messages = append(report.messages, fmt.Sprintf(format, args...))
+}
+
+//println adds a value formatted as a line of text to the report
+func (report *report) println(value interface{}) {
+	report.messages = append(report.messages, fmt.Sprint(value))
+}
+
+//set adds a named value of the result to the report
+func (report *report) set(name string, value interface{}) {
+	if report.result == nil {
+		report.result = make(map[string]interface{})
+	}
+	report.result[name] = value
+}
+
+//check adds an error to the report, reporting whether there was one
+func (report *report) check(err error) bool {
+	if err != nil {
+		report.err = err
+		return true
+	}
+	return false
+}
+
+//list adds a list of values under a heading to the report
+func (report *report) list(column string, values []string) {
+	report.column, report.values = column, values
+}
+
+//table adds a table to the report
+func (report *report) table(headers []string, rows [][]string) {
+	report.headers, report.rows = headers, rows
+}
+
+//empty checks whether the command reported nothing
+func (report *report) empty() bool {
+	return len(report.messages) == 0 && report.result == nil && report.err == nil && report.column == "" && report.headers == nil
+}
+
+//write writes the report in the given format
+func (report *report) write(w io.Writer, format string) error {
+	if report.empty() {
+		return nil
+	}
+	switch format {
+	case formatJSON:
+		return report.writeJSON(w)
+	case formatCSV:
+		return report.writeCSV(w)
+	}
+	return report.writeText(w)
+}
+
+//writeText writes the lines of text, the list of values joined by commas, and the table aligned in columns
+func (report *report) writeText(w io.Writer) error {
+	for _, message := range report.messages {
+		fmt.Fprintln(w, message)
+	}
+	if report.err != nil {
+		fmt.Fprintln(w, report.err.Error())
+	}
+	if err := pretty.Printer(report.values, w); err != nil {
+		return err
+	}
+	if report.headers != nil {
+		tw := tabwriter.NewWriter(w, 0, 0, 4, ' ', 0)
+		fmt.Fprintln(tw, strings.Join(report.headers, "\t"))
+		for _, row := range report.rows {
+			fmt.Fprintln(tw, strings.Join(row, "\t"))
+		}
+		return tw.Flush()
+	}
+	return nil
+}
+
+//jsonReport is the JSON object written for a command
+type jsonReport struct {
+	Command  string                 `json:"command"`
+	Error    string                 `json:"error,omitempty"`
+	Messages []string               `json:"messages,omitempty"`
+	Result   map[string]interface{} `json:"result,omitempty"`
+	Values   []string               `json:"values,omitempty"`
+	Rows     []map[string]string    `json:"rows,omitempty"`
+}
+
+//writeJSON writes the report as a JSON object on a single line, keying the cells of each row by its column
+func (report *report) writeJSON(w io.Writer) error {
+	object := jsonReport{Command: report.command, Messages: report.messages, Result: report.result, Values: report.values}
+	if report.err != nil {
+		object.Error = report.err.Error()
+	}
+	if report.column != "" && object.Values == nil {
+		object.Values = []string{}
+	}
+	if report.headers != nil {
+		object.Rows = []map[string]string{}
+	}
+	for _, row := range report.rows {
+		cells := make(map[string]string)
+		for ii, cell := range row {
+			cells[jsonKey(report.headers[ii])] = cell
+		}
+		object.Rows = append(object.Rows, cells)
+	}
+	return json.NewEncoder(w).Encode(object)
+}
+
+//jsonKey converts a column heading into a JSON key, such as slot_no for Slot No.
+func jsonKey(heading string) string {
+	return strings.Join(strings.Fields(strings.ToLower(strings.Replace(heading, ".", "", -1))), "_")
+}
+
+//writeCSV writes the error, the result, the list of values, and the table each as a header record followed by
+//value records with as many fields, the header led by the word command and each value record by the name of
+//the command. Lines of text are written as message records only when the command reports nothing else
+func (report *report) writeCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	block := func(headers []string, rows ...[]string) {
+		cw.Write(append([]string{"command"}, headers...))
+		for _, row := range rows {
+			cw.Write(append([]string{report.command}, row...))
+		}
+	}
+	if report.err != nil {
+		block([]string{"error"}, []string{report.err.Error()})
+	}
+	if report.result != nil {
+		var names []string
+		for name := range report.result {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		var values []string
+		for _, name := range names {
+			value, err := csvValue(report.result[name])
+			if err != nil {
+				return err
+			}
+			values = append(values, value)
+		}
+		block(names, values)
+	}
+	if report.column != "" {
+		var rows [][]string
+		for _, value := range report.values {
+			rows = append(rows, []string{value})
+		}
+		block([]string{report.column}, rows...)
+	}
+	if report.headers != nil {
+		block(report.headers, report.rows...)
+	}
+	if report.result == nil && report.column == "" && report.headers == nil && len(report.messages) > 0 {
+		var rows [][]string
+		for _, message := range report.messages {
+			rows = append(rows, []string{message})
+		}
+		block([]string{"message"}, rows...)
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+//csvValue formats a value of the result as a field, encoding lists and objects as JSON
+func csvValue(value interface{}) (string, error) {
+	switch value.(type) {
+	case string, int, bool:
+		return fmt.Sprint(value), nil
+	}
+	data, err := json.Marshal(value)
+	return string(data), err
+}
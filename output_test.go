@@ -0,0 +1,57 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+func Test_report_write(t *testing.T) {
+	status := &report{command: "status"}
+	status.table([]string{"Slot No.", "Registration No"}, [][]string{{"1", "KA-01-HH-1234"}, {"3", "KA-01-HH-9999"}})
+	colour := &report{command: "slot_numbers_for_cars_with_colour"}
+	colour.list("Slot No.", []string{"1", "3"})
+	park := &report{command: "park"}
+	park.printf("Allocated slot number: %v", 1)
+	park.set("slot", 1)
+	leave := &report{command: "leave"}
+	leave.printf("Slot number 1 is free")
+	leave.set("registration", "KA-01-HH-1234")
+	leave.set("slots", []int{1, 2})
+	closed := &report{command: "close_slot"}
+	closed.printf("Slot number 2 is closed")
+	failed := &report{command: "leave"}
+	failed.check(errNotFound)
+
+	tests := []struct {
+		name   string
+		report *report
+		format string
+		want   string
+	}{
+		{name: "Text table", report: status, format: formatText, want: "Slot No.    Registration No\n1           KA-01-HH-1234\n3           KA-01-HH-9999\n"},
+		{name: "JSON table", report: status, format: formatJSON,
+			want: `{"command":"status","rows":[{"registration_no":"KA-01-HH-1234","slot_no":"1"},{"registration_no":"KA-01-HH-9999","slot_no":"3"}]}` + "\n"},
+		{name: "CSV table", report: status, format: formatCSV, want: "command,Slot No.,Registration No\nstatus,1,KA-01-HH-1234\nstatus,3,KA-01-HH-9999\n"},
+		{name: "Text list", report: colour, format: formatText, want: "1, 3\n"},
+		{name: "JSON list", report: colour, format: formatJSON, want: `{"command":"slot_numbers_for_cars_with_colour","values":["1","3"]}` + "\n"},
+		{name: "CSV list", report: colour, format: formatCSV, want: "command,Slot No.\nslot_numbers_for_cars_with_colour,1\nslot_numbers_for_cars_with_colour,3\n"},
+		{name: "JSON result", report: park, format: formatJSON, want: `{"command":"park","messages":["Allocated slot number: 1"],"result":{"slot":1}}` + "\n"},
+		{name: "CSV result", report: leave, format: formatCSV, want: "command,registration,slots\nleave,KA-01-HH-1234,\"[1,2]\"\n"},
+		{name: "CSV message", report: closed, format: formatCSV, want: "command,message\nclose_slot,Slot number 2 is closed\n"},
+		{name: "Text error", report: failed, format: formatText, want: "Not found\n"},
+		{name: "JSON error", report: failed, format: formatJSON, want: `{"command":"leave","error":"Not found"}` + "\n"},
+		{name: "CSV error", report: failed, format: formatCSV, want: "command,error\nleave,Not found\n"},
+		{name: "Nothing reported", report: &report{command: "exit"}, format: formatJSON, want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got bytes.Buffer
+			if err := tt.report.write(&got, tt.format); err != nil {
+				t.Fatal(err)
+			}
+			if got.String() != tt.want {
+				t.Errorf("report.write() = %q, want %q", got.String(), tt.want)
+			}
+		})
+	}
+}
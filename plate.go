@@ -0,0 +1,152 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+//plateFormat is a jurisdiction's format of registration numbers
+type plateFormat interface {
+	//normalize checks a registration number, ignoring case, spacing, and dashes, and returns the form stored,
+	//or false when the registration number is invalid
+	normalize(registration string) (string, bool)
+	//example returns a valid registration number in the form stored
+	example() string
+	//String returns the name selecting the format with the -plates option
+	String() string
+}
+
+//errUnknownPlates reports a format of registration numbers which does not exist
+var errUnknownPlates = errors.New("Unknown registration number format, expected any, india, singapore, or uk")
+
+//parsePlates selects a format of registration numbers by name: any, india, singapore, or uk
+func parsePlates(name string) (plateFormat, error) {
+	switch strings.ToLower(name) {
+	case "any":
+		return anyPlate{}, nil
+	case "india":
+		return indiaPlate{}, nil
+	case "singapore":
+		return singaporePlate{}, nil
+	case "uk":
+		return ukPlate{}, nil
+	}
+	return nil, errUnknownPlates
+}
+
+//plateError reports a registration number invalid in the format of the carpark
+type plateError struct {
+	registration string      //Registration number given
+	format       plateFormat //Format of registration numbers expected
+}
+
+func (err *plateError) Error() string {
+	return fmt.Sprintf("Invalid %v registration number %q, expected e.g. %v", err.format, err.registration, err.format.example())
+}
+
+//separators matches the runs of spaces and dashes between the parts of a registration number
+var separators = regexp.MustCompile(`[\s-]+`)
+
+//compact upper cases a registration number and strips its spaces and dashes, giving the key under which
+//the carpark looks it up, so that every spelling of a registration number finds the same vehicle
+func compact(registration string) string {
+	return separators.ReplaceAllString(strings.ToUpper(registration), "")
+}
+
+//anyPlate accepts any registration number, joining the parts it is written in with single dashes
+type anyPlate struct{}
+
+func (anyPlate) normalize(registration string) (string, bool) {
+	registration = strings.Trim(separators.ReplaceAllString(strings.ToUpper(registration), "-"), "-")
+	return registration, registration != ""
+}
+
+func (anyPlate) example() string {
+	return "KA-01-HH-1234"
+}
+
+func (anyPlate) String() string {
+	return "any"
+}
+
+//indiaPlate accepts Indian registration numbers: state, district, optional series, and number, joined with dashes
+type indiaPlate struct{}
+
+var indiaPattern = regexp.MustCompile(`^([A-Z]{2})([0-9]{1,2})([A-Z]{0,3})([0-9]{1,4})$`)
+
+func (indiaPlate) normalize(registration string) (string, bool) {
+	parts := indiaPattern.FindStringSubmatch(compact(registration))
+	if parts == nil {
+		return "", false
+	}
+	if len(parts[2]) == 1 {
+		parts[2] = "0" + parts[2]
+	}
+	var fields []string
+	for _, part := range parts[1:] {
+		if part != "" {
+			fields = append(fields, part)
+		}
+	}
+	return strings.Join(fields, "-"), true
+}
+
+func (indiaPlate) example() string {
+	return "KA-01-HH-1234"
+}
+
+func (indiaPlate) String() string {
+	return "india"
+}
+
+//singaporePlate accepts Singapore registration numbers: prefix, number, and checksum letter, without separators
+type singaporePlate struct{}
+
+var singaporePattern = regexp.MustCompile(`^[A-Z]{1,3}[1-9][0-9]{0,3}[A-Z]$`)
+
+func (singaporePlate) normalize(registration string) (string, bool) {
+	registration = compact(registration)
+	return registration, singaporePattern.MatchString(registration)
+}
+
+func (singaporePlate) example() string {
+	return "SBA1234A"
+}
+
+func (singaporePlate) String() string {
+	return "singapore"
+}
+
+//ukPlate accepts current UK registration numbers: area, age identifier, and three letters, without separators
+type ukPlate struct{}
+
+var ukPattern = regexp.MustCompile(`^[A-Z]{2}[0-9]{2}[A-Z]{3}$`)
+
+func (ukPlate) normalize(registration string) (string, bool) {
+	registration = compact(registration)
+	return registration, ukPattern.MatchString(registration)
+}
+
+func (ukPlate) example() string {
+	return "AB12CDE"
+}
+
+func (ukPlate) String() string {
+	return "uk"
+}
+
+//Check a registration number in the format of the carpark, any when unset, and return the form stored.
+//The caller holds the carpark lock
+func (carpark *Carpark) plate(registration string) (string, error) {
+	format := carpark.plates
+	if format == nil {
+		format = anyPlate{}
+	}
+	normalized, ok := format.normalize(registration)
+	if !ok {
+		return "", &plateError{registration: registration, format: format}
+	}
+	return normalized, nil
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"testing"
+)
+
+func Test_plateFormat_normalize(t *testing.T) {
+	tests := []struct {
+		format       string
+		registration string
+		want         string
+		wantOk       bool
+	}{
+		{format: "any", registration: "KA-01-HH-1234", want: "KA-01-HH-1234", wantOk: true},
+		{format: "any", registration: " ka 01--hh 1234 ", want: "KA-01-HH-1234", wantOk: true},
+		{format: "any", registration: " - ", wantOk: false},
+		{format: "india", registration: "ka01hh1234", want: "KA-01-HH-1234", wantOk: true},
+		{format: "india", registration: "KA 1 P 333", want: "KA-01-P-333", wantOk: true},
+		{format: "india", registration: "DL-12-9999", want: "DL-12-9999", wantOk: true},
+		{format: "india", registration: "KA-01-HH-12345", wantOk: false},
+		{format: "singapore", registration: "sba 1234 a", want: "SBA1234A", wantOk: true},
+		{format: "singapore", registration: "E-12-J", want: "E12J", wantOk: true},
+		{format: "singapore", registration: "SBA0123A", wantOk: false},
+		{format: "uk", registration: "ab12 cde", want: "AB12CDE", wantOk: true},
+		{format: "uk", registration: "A123BCD", wantOk: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format+" "+tt.registration, func(t *testing.T) {
+			format, err := parsePlates(tt.format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, ok := format.normalize(tt.registration)
+			if ok != tt.wantOk || (ok && got != tt.want) {
+				t.Errorf("%v.normalize() = %v, %v, want %v, %v", format, got, ok, tt.want, tt.wantOk)
+			}
+		})
+	}
+}
+
+func TestCarpark_normalizedRegistration(t *testing.T) {
+	carpark := &Carpark{plates: indiaPlate{}}
+	carpark.init(nil, 6)
+	if _, _, err := carpark.insertCar(defaultVehicleTypes.newVehicle("car", "ka 01 hh 1234", "White")); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := carpark.insertCar(defaultVehicleTypes.newVehicle("car", "KA01HH1234", "White")); err != errDuplicate {
+		t.Errorf("Carpark.insertCar() error = %v, want %v", err, errDuplicate)
+	}
+	if _, _, err := carpark.insertCar(defaultVehicleTypes.newVehicle("car", "SBA1234A", "White")); err == nil || err.Error() != `Invalid india registration number "SBA1234A", expected e.g. KA-01-HH-1234` {
+		t.Errorf("Carpark.insertCar() error = %v", err)
+	}
+	if got, err := carpark.getCarWithRegistrationNo("Ka-01-Hh-1234"); err != nil || got != 1 {
+		t.Errorf("Carpark.getCarWithRegistrationNo() = %v, %v, want 1", got, err)
+	}
+	if got := carpark.getStatus(); *got[0].getRegistration() != "KA-01-HH-1234" {
+		t.Errorf("Carpark.getStatus() registration = %v, want KA-01-HH-1234", *got[0].getRegistration())
+	}
+}
+
+func TestCarpark_anyPlateSpellings(t *testing.T) {
+	carpark := &Carpark{}
+	carpark.init(nil, 6)
+	if _, _, err := carpark.insertCar(defaultVehicleTypes.newVehicle("car", "ka01hh1234", "White")); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := carpark.insertCar(defaultVehicleTypes.newVehicle("car", "KA-01-HH-1234", "White")); err != errDuplicate {
+		t.Errorf("Carpark.insertCar() error = %v, want %v", err, errDuplicate)
+	}
+	if got, err := carpark.getCarWithRegistrationNo("KA 01 HH 1234"); err != nil || got != 1 {
+		t.Errorf("Carpark.getCarWithRegistrationNo() = %v, %v, want 1", got, err)
+	}
+	if got := carpark.getStatus(); *got[0].getRegistration() != "KA01HH1234" {
+		t.Errorf("Carpark.getStatus() registration = %v, want KA01HH1234", *got[0].getRegistration())
+	}
+	if _, err := carpark.removeCarWithRegistration("Ka-01-Hh-1234"); err != nil {
+		t.Errorf("Carpark.removeCarWithRegistration() error = %v", err)
+	}
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"container/heap"
+	"errors"
+	"minheap"
+	"sort"
+	"strings"
+	"time"
+)
+
+//Orders in which the waiting queue admits vehicles
+const (
+	queueFIFO     = "fifo"     //In order of arrival
+	queuePriority = "priority" //Emergency vehicles, then permit holders, then every other vehicle, each in order of arrival
+	queueOff      = "off"      //No queue, vehicles are turned away when the carpark is full
+)
+
+//Errors reported by the waiting queue
+var (
+	errUnknownQueue  = errors.New("Unknown waiting queue, expected fifo, priority, or off")
+	errQueueNotEmpty = errors.New("Sorry, vehicles are still waiting in the queue")
+)
+
+//waitingVehicle is a vehicle in the waiting queue, whose heap item holds its priority class
+type waitingVehicle struct {
+	minheap.Item
+	order   int     //Order in which the vehicle was queued, breaking ties within its priority class
+	vehicle Vehicle //Vehicle waiting
+}
+
+//waitingList is a min heap of waiting vehicles, ordered by priority class and then by the order they were queued
+type waitingList []*waitingVehicle
+
+func (list waitingList) Len() int {
+	return len(list)
+}
+
+func (list waitingList) Less(i int, j int) bool {
+	if list[i].Value != list[j].Value {
+		return list[i].Value < list[j].Value
+	}
+	return list[i].order < list[j].order
+}
+
+func (list waitingList) Swap(i int, j int) {
+	list[i], list[j] = list[j], list[i]
+}
+
+func (list *waitingList) Push(x interface{}) {
+	*list = append(*list, x.(*waitingVehicle))
+}
+
+func (list *waitingList) Pop() interface{} {
+	old := *list
+	item := old[len(old)-1]
+	*list = old[:len(old)-1]
+	return item
+}
+
+//waitingQueue holds vehicles waiting for enough slots to be freed
+type waitingQueue struct {
+	mode  string      //Either fifo or priority
+	items waitingList //Waiting vehicles, ordered by priority class and then arrival
+	seq   int         //Order given to the last vehicle queued
+}
+
+//priority returns the class of a waiting vehicle, where lower classes are admitted first
+func (queue *waitingQueue) priority(vehicle Vehicle) int {
+	switch {
+	case queue.mode != queuePriority:
+		return 0
+	case *vehicle.getPermits()&attrEmergency != 0:
+		return 0
+	case *vehicle.getPermits()&restricted != 0:
+		return 1
+	}
+	return 2
+}
+
+//push adds a vehicle to the back of its priority class
+func (queue *waitingQueue) push(vehicle Vehicle) {
+	queue.seq++
+	heap.Push(&queue.items, &waitingVehicle{Item: minheap.Item{Value: queue.priority(vehicle)}, order: queue.seq, vehicle: vehicle})
+}
+
+//remove takes a vehicle out of the queue, reporting whether it was waiting
+func (queue *waitingQueue) remove(registration string) bool {
+	for ii, item := range queue.items {
+		if compact(*item.vehicle.getRegistration()) == compact(registration) {
+			heap.Remove(&queue.items, ii)
+			return true
+		}
+	}
+	return false
+}
+
+//contains checks whether a vehicle is waiting
+func (queue *waitingQueue) contains(registration string) bool {
+	for _, item := range queue.items {
+		if compact(*item.vehicle.getRegistration()) == compact(registration) {
+			return true
+		}
+	}
+	return false
+}
+
+//vehicles lists the waiting vehicles in the order they would be admitted
+func (queue *waitingQueue) vehicles() []Vehicle {
+	items := append(waitingList(nil), queue.items...)
+	sort.Sort(items)
+	var vehicles []Vehicle
+	for _, item := range items {
+		vehicles = append(vehicles, item.vehicle)
+	}
+	return vehicles
+}
+
+//Queue vehicles arriving at a full carpark in the given order, or turn them away when the order is off
+func (carpark *Carpark) setQueue(mode string) error {
+	carpark.mu.Lock()
+	defer carpark.mu.Unlock()
+
+	if err := carpark.initStatus(); err != nil {
+		return err
+	}
+	mode = strings.ToLower(mode)
+	switch mode {
+	case queueFIFO, queuePriority:
+	case queueOff:
+		if carpark.queue != nil && carpark.queue.items.Len() > 0 {
+			return errQueueNotEmpty
+		}
+	default:
+		return errUnknownQueue
+	}
+	if err := carpark.record(&event{Type: eventQueue, Time: carpark.now(), Queue: mode}); err != nil {
+		return err
+	}
+	carpark.queueMode(mode)
+	carpark.changed()
+	return nil
+}
+
+//Return the waiting vehicles in the order they would be admitted
+func (carpark *Carpark) getWaiting() []Vehicle {
+	carpark.mu.Lock()
+	defer carpark.mu.Unlock()
+
+	if carpark.queue == nil {
+		return nil
+	}
+	return carpark.queue.vehicles()
+}
+
+//The following helpers expect the caller to hold the carpark lock
+
+//Set the order of the waiting queue, ordering the vehicles already waiting afresh
+func (carpark *Carpark) queueMode(mode string) {
+	if mode == queueOff {
+		carpark.queue = nil
+		return
+	}
+	if carpark.queue == nil {
+		carpark.queue = &waitingQueue{}
+	}
+	carpark.queue.mode = mode
+	for _, item := range carpark.queue.items {
+		item.Value = carpark.queue.priority(item.vehicle)
+	}
+	heap.Init(&carpark.queue.items)
+}
+
+//Check whether a vehicle is waiting in the queue
+func (carpark *Carpark) queued(registration string) bool {
+	return carpark.queue != nil && carpark.queue.contains(registration)
+}
+
+//Add a vehicle to the waiting queue
+func (carpark *Carpark) enqueue(vehicle Vehicle, now time.Time) error {
+	err := carpark.record(&event{Type: eventEnqueue, Time: now, Vehicle: strings.ToLower(vehicle.getType()),
+		Registration: *vehicle.getRegistration(), Colour: *vehicle.getColour(), Permits: vehicle.getPermits().names(permitNames)})
+	if err != nil {
+		return err
+	}
+	*vehicle.getArrival() = now
+	carpark.queue.push(vehicle)
+	carpark.changed()
+	return nil
+}
+
+//Park waiting vehicles while any of them fits, taking each time the first in queue order which fits,
+//and return the vehicles admitted
+func (carpark *Carpark) admit() []Vehicle {
+	if carpark.queue == nil {
+		return nil
+	}
+	var admitted []Vehicle
+	for found := true; found; {
+		found = false
+		for _, vehicle := range carpark.queue.vehicles() {
+			if _, err := carpark.park(vehicle, carpark.now()); err == nil {
+				admitted = append(admitted, vehicle)
+				found = true
+				break
+			}
+		}
+	}
+	return admitted
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+//registrations lists the registration numbers of vehicles
+func registrations(vehicles []Vehicle) []string {
+	var list []string
+	for _, vehicle := range vehicles {
+		list = append(list, *vehicle.getRegistration())
+	}
+	return list
+}
+
+func TestCarpark_waitingQueue(t *testing.T) {
+	dir, err := ioutil.TempDir("", "events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	fileName := filepath.Join(dir, "events.log")
+
+	carpark := &Carpark{clock: fixedClock(testTime)}
+	if carpark.events, err = openEventLog(fileName); err != nil {
+		t.Fatal(err)
+	}
+	defer carpark.events.file.Close()
+	carpark.init(nil, 4)
+	carpark.insertCar(defaultVehicleTypes.newVehicle("car", "KA-01-HH-0001", "White"))
+	carpark.insertCar(defaultVehicleTypes.newVehicle("car", "KA-01-HH-0002", "White"))
+	if err := carpark.setQueue("priority"); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, vehicle := range []Vehicle{
+		defaultVehicleTypes.newVehicle("car", "KA-01-HH-0003", "Red"),
+		withPermits(defaultVehicleTypes.newVehicle("motorcycle", "KA-01-MM-0001", "Black"), attrAccessible),
+		withPermits(defaultVehicleTypes.newVehicle("car", "KA-01-AM-0001", "White"), attrEmergency),
+	} {
+		if slotNo, _, err := carpark.insertCar(vehicle); err != nil || slotNo != 0 {
+			t.Fatalf("Carpark.insertCar() = %v, %v, want the vehicle queued", slotNo, err)
+		}
+	}
+	if _, _, err := carpark.insertCar(defaultVehicleTypes.newVehicle("car", "KA-01-HH-0003", "Red")); err != errDuplicate {
+		t.Errorf("Carpark.insertCar() error = %v, want %v", err, errDuplicate)
+	}
+	if err := carpark.setQueue("off"); err != errQueueNotEmpty {
+		t.Errorf("Carpark.setQueue() error = %v, want %v", err, errQueueNotEmpty)
+	}
+
+	tests := []struct {
+		name         string
+		leave        int
+		wantAdmitted []string
+		wantWaiting  []string
+	}{
+		{name: "Before any vehicle leaves", wantWaiting: []string{"KA-01-AM-0001", "KA-01-MM-0001", "KA-01-HH-0003"}},
+		{name: "Emergency vehicle admitted first", leave: 1, wantAdmitted: []string{"KA-01-AM-0001"}, wantWaiting: []string{"KA-01-MM-0001", "KA-01-HH-0003"}},
+		{name: "Only the vehicle which fits admitted", leave: 3, wantAdmitted: []string{"KA-01-MM-0001"}, wantWaiting: []string{"KA-01-HH-0003"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.leave != 0 {
+				receipt, err := carpark.removeCar(tt.leave)
+				if err != nil {
+					t.Fatalf("Carpark.removeCar() error = %v", err)
+				}
+				if got := registrations(receipt.admitted); !reflect.DeepEqual(got, tt.wantAdmitted) {
+					t.Errorf("Carpark.removeCar() admitted = %v, want %v", got, tt.wantAdmitted)
+				}
+			}
+			if got := registrations(carpark.getWaiting()); !reflect.DeepEqual(got, tt.wantWaiting) {
+				t.Errorf("Carpark.getWaiting() = %v, want %v", got, tt.wantWaiting)
+			}
+		})
+	}
+
+	//Replaying the event log rebuilds the queue
+	replayed := &Carpark{}
+	if err := replayed.replay(fileName, time.Time{}); err != nil {
+		t.Fatalf("replay() error = %v", err)
+	}
+	compareCarpark(t, replayed, carpark)
+	if got, want := registrations(replayed.getWaiting()), registrations(carpark.getWaiting()); !reflect.DeepEqual(got, want) {
+		t.Errorf("replay() waiting = %v, want %v", got, want)
+	}
+}
+
+func TestCarpark_setQueue(t *testing.T) {
+	carpark := &Carpark{}
+	carpark.init(nil, 1)
+	carpark.insertCar(defaultVehicleTypes.newVehicle("motorcycle", "KA-01-MM-0001", "Black"))
+	if _, _, err := carpark.insertCar(defaultVehicleTypes.newVehicle("motorcycle", "KA-01-MM-0002", "Black")); err != errFull {
+		t.Errorf("Carpark.insertCar() without a queue error = %v, want %v", err, errFull)
+	}
+	if err := carpark.setQueue("lifo"); err != errUnknownQueue {
+		t.Errorf("Carpark.setQueue() error = %v, want %v", err, errUnknownQueue)
+	}
+
+	//A fifo queue admits in order of arrival whatever the permits
+	carpark.setQueue("FIFO")
+	carpark.insertCar(defaultVehicleTypes.newVehicle("motorcycle", "KA-01-MM-0002", "Black"))
+	carpark.insertCar(withPermits(defaultVehicleTypes.newVehicle("motorcycle", "KA-01-AM-0001", "White"), attrEmergency))
+	want := []string{"KA-01-MM-0002", "KA-01-AM-0001"}
+	if got := registrations(carpark.getWaiting()); !reflect.DeepEqual(got, want) {
+		t.Errorf("Carpark.getWaiting() = %v, want %v", got, want)
+	}
+
+	//Switching to priority order reorders the vehicles already waiting
+	carpark.setQueue("priority")
+	want = []string{"KA-01-AM-0001", "KA-01-MM-0002"}
+	if got := registrations(carpark.getWaiting()); !reflect.DeepEqual(got, want) {
+		t.Errorf("Carpark.getWaiting() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,289 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+//historyLimit is the number of commands kept in the history file
+const historyLimit = 500
+
+//errNotTerminal reports an input which is not an interactive terminal
+var errNotTerminal = errors.New("Input is not a terminal")
+
+//lineEditor reads commands typed at a terminal in raw mode, one line per Read, offering a prompt,
+//cursor movement, a history of earlier commands, and tab completion
+type lineEditor struct {
+	in          *bufio.Reader                        //Keys typed at the terminal
+	out         io.Writer                            //Terminal echoing the line being edited
+	prompt      func() string                        //Returns the prompt shown before each line
+	complete    func(line string) (string, []string) //Returns the word before the cursor and the words completing it
+	history     []string                             //Commands entered, from the oldest
+	historyFile string                               //File the history is kept in, "" when it is not kept
+	pending     []byte                               //Part of the last line entered not yet read
+}
+
+//Read returns the next line entered, ending with a newline, so that the editor can feed a scanner
+func (editor *lineEditor) Read(p []byte) (int, error) {
+	if len(editor.pending) == 0 {
+		line, err := editor.readLine()
+		if err != nil {
+			return 0, err
+		}
+		editor.pending = []byte(line + "\n")
+	}
+	n := copy(p, editor.pending)
+	editor.pending = editor.pending[n:]
+	return n, nil
+}
+
+//readLine shows the prompt and edits a line until it is entered, returning io.EOF for Ctrl-D on an empty line
+func (editor *lineEditor) readLine() (string, error) {
+	prompt := editor.prompt()
+	var line []rune
+	cursor := 0
+	browsing := len(editor.history) //Position in the history of the line shown, the length of the history for a new line
+	draft := ""                     //New line kept while browsing the history
+	recall := func(position int) {
+		if position < 0 || position > len(editor.history) {
+			return
+		}
+		if browsing == len(editor.history) {
+			draft = string(line)
+		}
+		browsing = position
+		if position == len(editor.history) {
+			line = []rune(draft)
+		} else {
+			line = []rune(editor.history[position])
+		}
+		cursor = len(line)
+	}
+	editor.redraw(prompt, line, cursor)
+	for {
+		r, _, err := editor.in.ReadRune()
+		if err != nil {
+			return "", err
+		}
+		switch r {
+		case '\r', '\n': //Enter
+			fmt.Fprint(editor.out, "\r\n")
+			editor.remember(string(line))
+			return string(line), nil
+		case 4: //Ctrl-D ends the input on an empty line, and otherwise deletes the character under the cursor
+			if len(line) == 0 {
+				fmt.Fprint(editor.out, "\r\n")
+				return "", io.EOF
+			}
+			if cursor < len(line) {
+				line = append(line[:cursor], line[cursor+1:]...)
+			}
+		case 3: //Ctrl-C abandons the line
+			fmt.Fprint(editor.out, "^C\r\n")
+			line, cursor, browsing = nil, 0, len(editor.history)
+		case 127, 8: //Backspace
+			if cursor > 0 {
+				line = append(line[:cursor-1], line[cursor:]...)
+				cursor--
+			}
+		case 1: //Ctrl-A moves to the start of the line
+			cursor = 0
+		case 5: //Ctrl-E moves to the end of the line
+			cursor = len(line)
+		case 21: //Ctrl-U deletes up to the cursor
+			line, cursor = line[cursor:], 0
+		case 11: //Ctrl-K deletes from the cursor
+			line = line[:cursor]
+		case '\t':
+			line, cursor = editor.completeWord(prompt, line, cursor)
+		case 27: //Escape sequence of an arrow, home, end, or delete key
+			switch editor.escape() {
+			case "[A", "OA":
+				recall(browsing - 1)
+			case "[B", "OB":
+				recall(browsing + 1)
+			case "[C", "OC":
+				if cursor < len(line) {
+					cursor++
+				}
+			case "[D", "OD":
+				if cursor > 0 {
+					cursor--
+				}
+			case "[H", "OH", "[1~":
+				cursor = 0
+			case "[F", "OF", "[4~":
+				cursor = len(line)
+			case "[3~":
+				if cursor < len(line) {
+					line = append(line[:cursor], line[cursor+1:]...)
+				}
+			}
+		default:
+			if unicode.IsPrint(r) {
+				line = append(line[:cursor], append([]rune{r}, line[cursor:]...)...)
+				cursor++
+			}
+		}
+		editor.redraw(prompt, line, cursor)
+	}
+}
+
+//escape reads the rest of an escape sequence, such as [A for the up arrow
+func (editor *lineEditor) escape() string {
+	var sequence []rune
+	for {
+		r, _, err := editor.in.ReadRune()
+		if err != nil {
+			return string(sequence)
+		}
+		sequence = append(sequence, r)
+		if len(sequence) > 1 && (unicode.IsLetter(r) || r == '~') {
+			return string(sequence)
+		}
+	}
+}
+
+//redraw shows the prompt and line, and places the cursor
+func (editor *lineEditor) redraw(prompt string, line []rune, cursor int) {
+	fmt.Fprintf(editor.out, "\r%v%v\x1b[K", prompt, string(line))
+	if cursor < len(line) {
+		fmt.Fprintf(editor.out, "\x1b[%vD", len(line)-cursor)
+	}
+}
+
+//completeWord extends the word before the cursor by the part its completions share, adding a space after
+//the only completion, and lists the completions when they share nothing more
+func (editor *lineEditor) completeWord(prompt string, line []rune, cursor int) ([]rune, int) {
+	word, candidates := editor.complete(string(line[:cursor]))
+	if len(candidates) == 0 {
+		fmt.Fprint(editor.out, "\a")
+		return line, cursor
+	}
+	shared := candidates[0]
+	for _, candidate := range candidates[1:] {
+		for !strings.HasPrefix(candidate, shared) {
+			shared = shared[:len(shared)-1]
+		}
+	}
+	insert := []rune(strings.TrimPrefix(shared, word))
+	if len(candidates) == 1 {
+		insert = append(insert, ' ')
+	}
+	if len(insert) == 0 {
+		fmt.Fprintf(editor.out, "\r\n%v\r\n", strings.Join(candidates, "  "))
+		return line, cursor
+	}
+	line = append(line[:cursor], append(insert, line[cursor:]...)...)
+	return line, cursor + len(insert)
+}
+
+//remember adds a command to the history and appends it to the history file, skipping blank lines and repeats
+func (editor *lineEditor) remember(line string) {
+	if strings.TrimSpace(line) == "" || (len(editor.history) > 0 && editor.history[len(editor.history)-1] == line) {
+		return
+	}
+	editor.history = append(editor.history, line)
+	if editor.historyFile == "" {
+		return
+	}
+	file, err := os.OpenFile(editor.historyFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+	fmt.Fprintln(file, line)
+}
+
+//loadHistory reads the latest commands of a history file, keeping the file to its limit
+func loadHistory(fileName string) []string {
+	content, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return nil
+	}
+	history := strings.Split(strings.TrimRight(string(content), "\n"), "\n")
+	if len(history) > historyLimit {
+		history = history[len(history)-historyLimit:]
+		ioutil.WriteFile(fileName, []byte(strings.Join(history, "\n")+"\n"), 0600)
+	}
+	return history
+}
+
+//defaultHistoryFile returns the history file in the home directory, or "" when there is none
+func defaultHistoryFile() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".carpark_history")
+}
+
+//prompt shows the number of slots occupied out of the slots in the carpark, once it is created
+func prompt(carpark *Carpark) string {
+	size := carpark.getSize()
+	if size == 0 {
+		return "carpark> "
+	}
+	occupied := 0
+	for _, vehicle := range carpark.getStatus() {
+		occupied += vehicle.getSlotsNeeded()
+	}
+	return fmt.Sprintf("carpark [%v/%v]> ", occupied, size)
+}
+
+//completions returns the word before the cursor and the words completing it: command names for the first word,
+//and the colours and registration numbers of the parked vehicles for the others
+func completions(carpark *Carpark, line string) (string, []string) {
+	word := line[strings.LastIndexFunc(line, unicode.IsSpace)+1:]
+	var words []string
+	if strings.TrimSpace(line) == word {
+		words = consoleCommands.names
+	} else {
+		for _, vehicle := range carpark.getStatus() {
+			words = append(words, *vehicle.getRegistration(), *vehicle.getColour())
+		}
+	}
+	seen := make(map[string]bool)
+	var candidates []string
+	for _, candidate := range words {
+		if strings.ContainsAny(candidate, " \t") {
+			candidate = `"` + candidate + `"`
+		}
+		if strings.HasPrefix(candidate, word) && !seen[candidate] {
+			seen[candidate] = true
+			candidates = append(candidates, candidate)
+		}
+	}
+	sort.Strings(candidates)
+	return word, candidates
+}
+
+//openConsole reads commands from the interactive input, through a line editor when it is a terminal,
+//and returns a function restoring the terminal
+func openConsole(carpark *Carpark, historyFile string) (*bufio.Scanner, func()) {
+	file, ok := inputInteractive.(*os.File)
+	if !ok {
+		return bufio.NewScanner(inputInteractive), func() {}
+	}
+	restore, err := makeRaw(file.Fd())
+	if err != nil {
+		return bufio.NewScanner(inputInteractive), func() {}
+	}
+	editor := &lineEditor{
+		in:          bufio.NewReader(file),
+		out:         outStream,
+		prompt:      func() string { return prompt(carpark) },
+		complete:    func(line string) (string, []string) { return completions(carpark, line) },
+		history:     loadHistory(historyFile),
+		historyFile: historyFile,
+	}
+	return bufio.NewScanner(editor), restore
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func parkedCarpark() *Carpark {
+	carpark := &Carpark{}
+	carpark.init(nil, 6)
+	carpark.insertCar(defaultVehicleTypes.newVehicle("car", "KA-01-HH-1234", "White"))
+	carpark.insertCar(defaultVehicleTypes.newVehicle("motorcycle", "KA-01-HH-9999", "Sky Blue"))
+	return carpark
+}
+
+func Test_prompt(t *testing.T) {
+	if got := prompt(&Carpark{}); got != "carpark> " {
+		t.Errorf("prompt() = %q, want %q", got, "carpark> ")
+	}
+	if got := prompt(parkedCarpark()); got != "carpark [3/6]> " {
+		t.Errorf("prompt() = %q, want %q", got, "carpark [3/6]> ")
+	}
+}
+
+func Test_completions(t *testing.T) {
+	carpark := parkedCarpark()
+
+	tests := []struct {
+		name           string
+		line           string
+		wantWord       string
+		wantCandidates []string
+	}{
+		{name: "Command names", line: "le", wantWord: "le", wantCandidates: []string{"leave", "leave_by_registration"}},
+		{name: "Command names after spaces", line: "  set_", wantWord: "set_", wantCandidates: []string{"set_attribute", "set_overflow", "set_zone"}},
+		{name: "Registrations", line: "leave_by_registration KA-01-HH-1", wantWord: "KA-01-HH-1", wantCandidates: []string{"KA-01-HH-1234"}},
+		{name: "Colours quoted", line: `registration_numbers_for_cars_with_colour "S`, wantWord: `"S`, wantCandidates: []string{`"Sky Blue"`}},
+		{name: "Registrations and colours", line: "quote ", wantWord: "", wantCandidates: []string{`"Sky Blue"`, "KA-01-HH-1234", "KA-01-HH-9999", "White"}},
+		{name: "Nothing completes", line: "park X", wantWord: "X", wantCandidates: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotWord, gotCandidates := completions(carpark, tt.line)
+			if gotWord != tt.wantWord {
+				t.Errorf("completions() word = %q, want %q", gotWord, tt.wantWord)
+			}
+			if !reflect.DeepEqual(gotCandidates, tt.wantCandidates) {
+				t.Errorf("completions() candidates = %q, want %q", gotCandidates, tt.wantCandidates)
+			}
+		})
+	}
+}
+
+func Test_lineEditor(t *testing.T) {
+	carpark := parkedCarpark()
+	dir, err := ioutil.TempDir("", "repl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	historyFile := filepath.Join(dir, "history")
+
+	editor := func(keys string) *lineEditor {
+		return &lineEditor{
+			in:          bufio.NewReader(strings.NewReader(keys)),
+			out:         &bytes.Buffer{},
+			prompt:      func() string { return prompt(carpark) },
+			complete:    func(line string) (string, []string) { return completions(carpark, line) },
+			history:     loadHistory(historyFile),
+			historyFile: historyFile,
+		}
+	}
+	scan := func(editor *lineEditor) []string {
+		var lines []string
+		scanner := bufio.NewScanner(editor)
+		for scanner.Scan() {
+			lines = append(lines, scanner.Text())
+		}
+		return lines
+	}
+
+	tests := []struct {
+		name        string
+		keys        string
+		want        []string
+		wantHistory string
+	}{
+		{name: "Editing",
+			keys: "stat\t\r" + //Completes the only command
+				"leave 2\x7f1\r" + //Backspace
+				"\x1b[A\x1b[A\r" + //Recalls the command before last
+				"ark\x01p\x05 KA\x1b[D\x1b[D\x1b[3~\r" + //Moves to the start, the end, and back, then deletes
+				"le\t\r" + //Completes the part shared by two commands
+				"oops\x03" + //Abandons the line
+				"\x04", //Ends the input
+			want:        []string{"status ", "leave 1", "status ", "park A", "leave"},
+			wantHistory: "status \nleave 1\nstatus \npark A\nleave\n",
+		},
+		{name: "History kept",
+			keys:        "\x1b[A\x1b[A\x1b[A\x1b[B\r\r",
+			want:        []string{"park A", ""},
+			wantHistory: "status \nleave 1\nstatus \npark A\nleave\npark A\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := scan(editor(tt.keys)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("lineEditor lines = %q, want %q", got, tt.want)
+			}
+			content, err := ioutil.ReadFile(historyFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := string(content); got != tt.wantHistory {
+				t.Errorf("lineEditor history = %q, want %q", got, tt.wantHistory)
+			}
+		})
+	}
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"time"
+)
+
+//reservationLead is how long before the start of its window a reservation starts keeping its slots from walk-ins
+const reservationLead = 30 * time.Minute
+
+//reservationGrace is how long after the start of its window a reservation is held for a vehicle which has not arrived
+const reservationGrace = 15 * time.Minute
+
+//reservationLayout formats the times of a reservation window for output
+const reservationLayout = "2006-01-02T15:04"
+
+//Errors reported when reserving slots
+var (
+	errReservationWindow = errors.New("Reservation must end after it starts, and in the future")
+	errAlreadyReserved   = errors.New("Vehicle already holds a reservation")
+)
+
+//reservation holds slots for a vehicle expected over a window of time
+type reservation struct {
+	registration string    //Registration number of the expected vehicle
+	vehicleType  string    //Type of the expected vehicle
+	slot         int       //First slot held
+	slots        int       //Number of slots held
+	from         time.Time //Start of the window
+	to           time.Time //End of the window
+}
+
+//expires returns the time at which the hold lapses if the vehicle has not arrived
+func (reservation *reservation) expires() time.Time {
+	if noShow := reservation.from.Add(reservationGrace); noShow.Before(reservation.to) {
+		return noShow
+	}
+	return reservation.to
+}
+
+//active checks whether the reservation keeps its slots from walk-ins at the given time
+func (reservation *reservation) active(now time.Time) bool {
+	return !now.Before(reservation.from.Add(-reservationLead)) && now.Before(reservation.to)
+}
+
+//overlaps checks whether the reservation wants any of 'slots' slots from 'slotNo' at some time from 'from' to 'to'
+func (reservation *reservation) overlaps(slotNo int, slots int, from time.Time, to time.Time) bool {
+	return slotNo < reservation.slot+reservation.slots && slotNo+slots > reservation.slot &&
+		from.Before(reservation.to) && to.After(reservation.from)
+}
+
+//Hold slots for a vehicle over a window of time, keeping them from other vehicles from shortly before
+//the window until it arrives or the hold lapses, and return the reservation. Slots taken now may be held
+//for a window which has not yet drawn near, on the expectation that they are free by then
+func (carpark *Carpark) reserve(registration string, vehicleType string, from time.Time, to time.Time) (*reservation, error) {
+	carpark.mu.Lock()
+	defer carpark.mu.Unlock()
+
+	if err := carpark.initStatus(); err != nil {
+		return nil, err
+	}
+	class, err := carpark.types().lookup(vehicleType)
+	if err != nil {
+		return nil, err
+	}
+	vehicle := class.vehicle(registration, "")
+	registration, err = carpark.plate(registration)
+	if err != nil {
+		return nil, err
+	}
+	now := carpark.now()
+	if !to.After(from) || !to.After(now) {
+		return nil, errReservationWindow
+	}
+	carpark.expire(now)
+	if _, ok := carpark.registrations[compact(registration)]; ok {
+		return nil, errDuplicate
+	}
+	if _, ok := carpark.reservations[compact(registration)]; ok {
+		return nil, errAlreadyReserved
+	}
+
+	//Hold the position the allocation strategy chooses among those not held during the window,
+	//and free now when the window is near
+	overlapping := func(slotNo int, slots int) bool {
+		for _, reservation := range carpark.reservations {
+			if reservation.overlaps(slotNo, slots, from, to) {
+				return true
+			}
+		}
+		return false
+	}
+	var candidates []candidate
+	if (&reservation{from: from, to: to}).active(now) {
+		candidates = carpark.positions(vehicle, overlapping)
+	} else {
+		candidates = carpark.suitable(vehicle, carpark.serviceable(vehicle.getSlotsNeeded()), overlapping)
+	}
+	if len(candidates) == 0 {
+		return nil, errFull
+	}
+	chosen := carpark.allocator().choose(candidates)
+	reservation := &reservation{registration: registration, vehicleType: strings.ToLower(vehicle.getType()),
+		slot: chosen.slot, slots: chosen.slots, from: from, to: to}
+	err = carpark.record(&event{Type: eventReserve, Time: now, Vehicle: reservation.vehicleType,
+		Registration: registration, Slot: chosen.slot, Window: &window{From: from, To: to}})
+	if err != nil {
+		return nil, err
+	}
+	carpark.hold(reservation)
+	carpark.changed()
+	return reservation, nil
+}
+
+//Return the reservations still held, ordered by slot
+func (carpark *Carpark) getReservations() []*reservation {
+	carpark.mu.Lock()
+	defer carpark.mu.Unlock()
+
+	carpark.expire(carpark.now())
+	var reservations []*reservation
+	for _, reservation := range carpark.reservations {
+		reservations = append(reservations, reservation)
+	}
+	sort.Slice(reservations, func(i, j int) bool {
+		if reservations[i].slot != reservations[j].slot {
+			return reservations[i].slot < reservations[j].slot
+		}
+		return reservations[i].from.Before(reservations[j].from)
+	})
+	return reservations
+}
+
+//The following helpers expect the caller to hold the carpark lock
+
+//Add a reservation to the reservation store
+func (carpark *Carpark) hold(held *reservation) {
+	if carpark.reservations == nil {
+		carpark.reservations = make(map[string]*reservation)
+	}
+	carpark.reservations[compact(held.registration)] = held
+}
+
+//Release the holds of vehicles which did not arrive within the grace period
+func (carpark *Carpark) expire(now time.Time) {
+	released := false
+	for registration, reservation := range carpark.reservations {
+		if !now.Before(reservation.expires()) {
+			delete(carpark.reservations, registration)
+			released = true
+		}
+	}
+	if released {
+		carpark.changed()
+	}
+}
+
+//Check whether any of 'slots' slots from 'slotNo' are held for a vehicle at the given time, keeping them from walk-ins
+func (carpark *Carpark) held(slotNo int, slots int, now time.Time) bool {
+	for _, reservation := range carpark.reservations {
+		if slotNo < reservation.slot+reservation.slots && slotNo+slots > reservation.slot && reservation.active(now) {
+			return true
+		}
+	}
+	return false
+}
+
+//Return the held position of an arriving vehicle if its held slots are free, or nil
+func (carpark *Carpark) claim(vehicle Vehicle) []candidate {
+	reservation, ok := carpark.reservations[compact(*vehicle.getRegistration())]
+	if !ok || reservation.vehicleType != strings.ToLower(vehicle.getType()) {
+		return nil
+	}
+	free := carpark.freeSlots()
+	for slotNo := reservation.slot; slotNo < reservation.slot+reservation.slots; slotNo++ {
+		if !free[slotNo] {
+			return nil
+		}
+	}
+	return []candidate{{slot: reservation.slot, slots: reservation.slots, gapStart: reservation.slot,
+		gapSize: reservation.slots, floor: carpark.floorOf(reservation.slot)}}
+}
+
+//List every position of 'slotsNeeded' slots in service, whether or not vehicles park there now
+func (carpark *Carpark) serviceable(slotsNeeded int) []candidate {
+	var candidates []candidate
+	for _, floor := range carpark.floors {
+		gapStart, gapSize := floor.firstSlot, 0
+		addGap := func() {
+			for slot := gapStart; slot+slotsNeeded <= gapStart+gapSize; slot++ {
+				candidates = append(candidates, candidate{slot: slot, slots: slotsNeeded, gapStart: gapStart, gapSize: gapSize, floor: floor})
+			}
+		}
+		for slotNo := floor.firstSlot; slotNo <= floor.maxSlot; slotNo++ {
+			if _, closed := floor.closed[slotNo]; closed {
+				addGap()
+				gapStart, gapSize = slotNo+1, 0
+				continue
+			}
+			gapSize++
+		}
+		addGap()
+	}
+	return candidates
+}
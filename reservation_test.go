@@ -0,0 +1,133 @@
+package main
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestCarpark_reserve(t *testing.T) {
+	now := testTime
+	carpark := &Carpark{clock: func() time.Time { return now }}
+	carpark.init(nil, 5)
+
+	tests := []struct {
+		name    string
+		at      time.Duration //Time of the step after testTime
+		reserve string        //Registration number to reserve a car for, or empty to park
+		vehicle Vehicle       //Vehicle to park
+		want    int
+		wantErr error
+	}{
+		{name: "Reserve a car from 10:00 to 12:00", reserve: "KA-01-RR-0001", want: 1},
+		{name: "Reserve a car from 10:30 to 13:00", reserve: "KA-01-RR-0002", want: 3},
+		{name: "Walk-in kept out of held slots", at: time.Hour, vehicle: defaultVehicleTypes.newVehicle("car", "KA-01-HH-0001", "White"), wantErr: errFull},
+		{name: "Walk-in takes the free slot", at: time.Hour, vehicle: defaultVehicleTypes.newVehicle("motorcycle", "KA-01-MM-0001", "Black"), want: 5},
+		{name: "Reserved car arrives", at: time.Hour, vehicle: defaultVehicleTypes.newVehicle("car", "KA-01-RR-0001", "Red"), want: 1},
+		{name: "Duplicate reservation", at: time.Hour, reserve: "KA-01-RR-0002", wantErr: errAlreadyReserved},
+		{name: "Hold of the late car still kept", at: 100 * time.Minute, vehicle: defaultVehicleTypes.newVehicle("car", "KA-01-HH-0002", "White"), wantErr: errFull},
+		{name: "Hold released after the grace period", at: 105 * time.Minute, vehicle: defaultVehicleTypes.newVehicle("car", "KA-01-HH-0002", "White"), want: 3},
+		{name: "Late car parks like a walk-in", at: 110 * time.Minute, vehicle: defaultVehicleTypes.newVehicle("car", "KA-01-RR-0002", "Red"), wantErr: errFull},
+	}
+	windows := map[string][2]time.Duration{
+		"KA-01-RR-0001": {time.Hour, 3 * time.Hour},
+		"KA-01-RR-0002": {90 * time.Minute, 4 * time.Hour},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now = testTime.Add(tt.at)
+			var got int
+			var err error
+			if tt.reserve != "" {
+				var reservation *reservation
+				window := windows[tt.reserve]
+				if reservation, err = carpark.reserve(tt.reserve, "car", testTime.Add(window[0]), testTime.Add(window[1])); err == nil {
+					got = reservation.slot
+				}
+			} else {
+				got, _, err = carpark.insertCar(tt.vehicle)
+			}
+			if err != tt.wantErr {
+				t.Fatalf("error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("slot = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCarpark_reserveWindow(t *testing.T) {
+	carpark := &Carpark{clock: fixedClock(testTime)}
+	carpark.init(nil, 2)
+	tests := []struct {
+		name    string
+		from    time.Duration
+		to      time.Duration
+		wantErr error
+	}{
+		{name: "Ends before it starts", from: 2 * time.Hour, to: time.Hour, wantErr: errReservationWindow},
+		{name: "Already over", from: -2 * time.Hour, to: -time.Hour, wantErr: errReservationWindow},
+		{name: "Held for the whole carpark", from: time.Hour, to: 2 * time.Hour},
+		{name: "Overlapping the held window", from: 70 * time.Minute, to: 3 * time.Hour, wantErr: errFull},
+		{name: "After the held window", from: 2 * time.Hour, to: 3 * time.Hour},
+	}
+	for ii, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registration := "KA-01-RR-000" + strconv.Itoa(ii)
+			_, err := carpark.reserve(registration, "car", testTime.Add(tt.from), testTime.Add(tt.to))
+			if err != tt.wantErr {
+				t.Errorf("Carpark.reserve() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCarpark_reserveLead(t *testing.T) {
+	now := testTime
+	carpark := &Carpark{clock: func() time.Time { return now }}
+	carpark.init(nil, 2)
+
+	tests := []struct {
+		name    string
+		at      time.Duration //Time of the step after testTime
+		reserve string        //Registration number to reserve a car for from 11:00 to 12:00, or empty to park
+		leave   int           //Slot to leave, or 0
+		vehicle Vehicle       //Vehicle to park
+		want    int
+		wantErr error
+	}{
+		{name: "Parked car fills the carpark", vehicle: defaultVehicleTypes.newVehicle("car", "KA-01-HH-0001", "White"), want: 1},
+		{name: "Slot taken now reserved for a later window", reserve: "KA-01-RR-0001", want: 1},
+		{name: "Parked car leaves", at: time.Hour, leave: 1},
+		{name: "Walk-in before the lead time takes the reserved slot", at: time.Hour, vehicle: defaultVehicleTypes.newVehicle("car", "KA-01-HH-0002", "White"), want: 1},
+		{name: "Walk-in leaves", at: 80 * time.Minute, leave: 1},
+		{name: "Walk-in within the lead time kept out", at: 90 * time.Minute, vehicle: defaultVehicleTypes.newVehicle("car", "KA-01-HH-0003", "White"), wantErr: errFull},
+		{name: "Reservation near its window needs free slots", at: 90 * time.Minute, reserve: "KA-01-RR-0002", wantErr: errFull},
+		{name: "Reserved car arrives", at: 2 * time.Hour, vehicle: defaultVehicleTypes.newVehicle("car", "KA-01-RR-0001", "Red"), want: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now = testTime.Add(tt.at)
+			var got int
+			var err error
+			switch {
+			case tt.reserve != "":
+				var reservation *reservation
+				if reservation, err = carpark.reserve(tt.reserve, "car", testTime.Add(2*time.Hour), testTime.Add(3*time.Hour)); err == nil {
+					got = reservation.slot
+				}
+			case tt.leave != 0:
+				_, err = carpark.removeCar(tt.leave)
+			default:
+				got, _, err = carpark.insertCar(tt.vehicle)
+			}
+			if err != tt.wantErr {
+				t.Fatalf("error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("slot = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+//Errors reported when resizing the carpark
+var (
+	errResize         = errors.New("Number of slots must be positive")
+	errShrinkReserved = errors.New("Sorry, slots beyond the new limit are reserved")
+)
+
+//relocationError reports the vehicles parked beyond the new limit of a carpark being shrunk
+type relocationError struct {
+	limit    int       //Highest slot remaining after the shrink
+	vehicles []Vehicle //Vehicles to relocate before shrinking
+}
+
+func (err *relocationError) Error() string {
+	var parked []string
+	for _, vehicle := range err.vehicles {
+		parked = append(parked, fmt.Sprintf("%v (slot %v)", *vehicle.getRegistration(), *vehicle.getSlot()))
+	}
+	return fmt.Sprintf("Sorry, vehicles parked beyond slot %v must be relocated first: %v", err.limit, strings.Join(parked, ", "))
+}
+
+//Add slots to the top floor of the carpark, admit waiting vehicles into them, and return the vehicles admitted
+func (carpark *Carpark) expand(slots int) ([]Vehicle, error) {
+	carpark.mu.Lock()
+	defer carpark.mu.Unlock()
+
+	if err := carpark.initStatus(); err != nil {
+		return nil, err
+	}
+	if slots <= 0 {
+		return nil, errResize
+	}
+	if err := carpark.record(&event{Type: eventResize, Time: carpark.now(), Resize: slots}); err != nil {
+		return nil, err
+	}
+	carpark.resize(slots)
+	carpark.changed()
+	return carpark.admit(), nil
+}
+
+//Remove slots from the top floor of the carpark, refusing when a vehicle is parked or slots are held in them
+func (carpark *Carpark) shrink(slots int) error {
+	carpark.mu.Lock()
+	defer carpark.mu.Unlock()
+
+	if err := carpark.initStatus(); err != nil {
+		return err
+	}
+	if slots <= 0 {
+		return errResize
+	}
+	top := carpark.floors[len(carpark.floors)-1]
+	limit := top.maxSlot - slots
+	if limit < top.firstSlot {
+		return errEmptyFloor
+	}
+	var beyond []Vehicle
+	for _, vehicle := range carpark.vehicles() {
+		if *vehicle.getSlot()+vehicle.getSlotsNeeded()-1 > limit {
+			beyond = append(beyond, vehicle)
+		}
+	}
+	if len(beyond) > 0 {
+		return &relocationError{limit: limit, vehicles: beyond}
+	}
+	carpark.expire(carpark.now())
+	for _, reservation := range carpark.reservations {
+		if reservation.slot+reservation.slots-1 > limit {
+			return errShrinkReserved
+		}
+	}
+	if err := carpark.record(&event{Type: eventResize, Time: carpark.now(), Resize: -slots}); err != nil {
+		return err
+	}
+	carpark.resize(-slots)
+	carpark.changed()
+	return nil
+}
+
+//Return the number of slots in the carpark
+func (carpark *Carpark) getSize() int {
+	carpark.mu.Lock()
+	defer carpark.mu.Unlock()
+
+	size := 0
+	for _, floor := range carpark.floors {
+		size += floor.size()
+	}
+	return size
+}
+
+//The following helpers expect the caller to hold the carpark lock
+
+//Add slots to the top floor, or remove them when 'slots' is negative, forgetting the zones and attributes of removed slots
+func (carpark *Carpark) resize(slots int) {
+	top := carpark.floors[len(carpark.floors)-1]
+	if slots > 0 {
+		top.grow(slots)
+		return
+	}
+	for slotNo := top.maxSlot + slots + 1; slotNo <= top.maxSlot; slotNo++ {
+		delete(carpark.zones, slotNo)
+		delete(carpark.attributes, slotNo)
+	}
+	top.shrink(top.maxSlot + slots)
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"container/list"
+	"reflect"
+	"testing"
+)
+
+func Test_floor_shrink(t *testing.T) {
+	tests := []struct {
+		name           string
+		emptySlots     []int
+		highestSlot    int
+		maxSlot        int
+		wantEmptySlots []int
+		wantHighest    int
+	}{
+		{name: "Never filled beyond the limit", emptySlots: []int{2}, highestSlot: 3, maxSlot: 4, wantEmptySlots: []int{2}, wantHighest: 3},
+		{name: "Empty slots beyond the limit dropped", emptySlots: []int{2, 5, 6}, highestSlot: 7, maxSlot: 5, wantEmptySlots: []int{2}, wantHighest: 4},
+		{name: "Trailing empty slots dropped", emptySlots: []int{1, 3, 4}, highestSlot: 6, maxSlot: 4, wantEmptySlots: []int{1}, wantHighest: 2},
+		{name: "Every slot empty", emptySlots: []int{1, 2, 3}, highestSlot: 3, maxSlot: 2, wantEmptySlots: nil, wantHighest: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			emptySlots := list.New()
+			for _, slotNo := range tt.emptySlots {
+				emptySlots.PushBack(slotNo)
+			}
+			floor := singleFloor(emptySlots, tt.highestSlot, 8)[0]
+			floor.shrink(tt.maxSlot)
+			var gotEmptySlots []int
+			for e := floor.emptySlots.Front(); e != nil; e = e.Next() {
+				gotEmptySlots = append(gotEmptySlots, e.Value.(int))
+			}
+			if !reflect.DeepEqual(gotEmptySlots, tt.wantEmptySlots) || floor.highestSlot != tt.wantHighest || floor.maxSlot != tt.maxSlot {
+				t.Errorf("floor.shrink() empty slots = %v, highest slot = %v, max slot = %v, want %v, %v, %v",
+					gotEmptySlots, floor.highestSlot, floor.maxSlot, tt.wantEmptySlots, tt.wantHighest, tt.maxSlot)
+			}
+		})
+	}
+}
+
+func TestCarpark_shrink(t *testing.T) {
+	carpark := &Carpark{}
+	carpark.init(nil, 2, 4)
+	carpark.insertCar(defaultVehicleTypes.newVehicle("car", "KA-01-HH-0001", "White"))
+	carpark.insertCar(defaultVehicleTypes.newVehicle("car", "KA-01-HH-0002", "White"))
+	carpark.insertCar(defaultVehicleTypes.newVehicle("motorcycle", "KA-01-MM-0001", "Black"))
+	carpark.removeCar(3)
+	carpark.setZone("bus", 6, 6)
+
+	err := carpark.shrink(2)
+	relocation, ok := err.(*relocationError)
+	if !ok || relocation.limit != 4 || !reflect.DeepEqual(registrations(relocation.vehicles), []string{"KA-01-MM-0001"}) {
+		t.Fatalf("Carpark.shrink() error = %v, want the motorcycle at slot 5 relocated", err)
+	}
+	if err := carpark.shrink(4); err != errEmptyFloor {
+		t.Errorf("Carpark.shrink() error = %v, want %v", err, errEmptyFloor)
+	}
+	if err := carpark.shrink(0); err != errResize {
+		t.Errorf("Carpark.shrink() error = %v, want %v", err, errResize)
+	}
+	if err := carpark.shrink(1); err != nil {
+		t.Fatalf("Carpark.shrink() error = %v", err)
+	}
+	if got := carpark.getSize(); got != 5 {
+		t.Errorf("Carpark.getSize() = %v, want 5", got)
+	}
+	if _, ok := carpark.zones[6]; ok {
+		t.Errorf("Carpark.shrink() kept the zone of removed slot 6")
+	}
+	if _, _, err := carpark.insertCar(defaultVehicleTypes.newVehicle("bus", "KA-01-BB-0001", "Red")); err != errFull {
+		t.Errorf("Carpark.insertCar() error = %v, want %v", err, errFull)
+	}
+}
+
+func TestCarpark_expand(t *testing.T) {
+	carpark := &Carpark{}
+	carpark.init(nil, 2)
+	carpark.setQueue("fifo")
+	carpark.insertCar(defaultVehicleTypes.newVehicle("car", "KA-01-HH-0001", "White"))
+	carpark.insertCar(defaultVehicleTypes.newVehicle("bus", "KA-01-BB-0001", "Red"))
+	carpark.insertCar(defaultVehicleTypes.newVehicle("motorcycle", "KA-01-MM-0001", "Black"))
+
+	admitted, err := carpark.expand(2)
+	if err != nil {
+		t.Fatalf("Carpark.expand() error = %v", err)
+	}
+	if got, want := registrations(admitted), []string{"KA-01-MM-0001"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Carpark.expand() admitted = %v, want %v", got, want)
+	}
+	if got := carpark.getSize(); got != 4 {
+		t.Errorf("Carpark.getSize() = %v, want 4", got)
+	}
+	if _, err := carpark.expand(-1); err != errResize {
+		t.Errorf("Carpark.expand() error = %v, want %v", err, errResize)
+	}
+}
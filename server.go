@@ -0,0 +1,299 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+//server exposes the carpark operations as JSON endpoints
+type server struct {
+	carpark *Carpark
+}
+
+//createRequest is the body of a request to create the carpark
+type createRequest struct {
+	Floors   []int  `json:"floors"`   //Number of slots on each floor, starting from the lowest floor
+	Strategy string `json:"strategy"` //Allocation strategy, defaults to first_fit
+}
+
+//createResponse is the body returned after creating the carpark
+type createResponse struct {
+	Slots  int `json:"slots"`
+	Floors int `json:"floors"`
+}
+
+//parkRequest is the body of a request to park a vehicle
+type parkRequest struct {
+	Registration string   `json:"registration"`
+	Colour       string   `json:"colour"`
+	Type         string   `json:"type"`    //Name of a vehicle type, such as car, motorcycle, or bus
+	Permits      []string `json:"permits"` //Any of accessible, ev, reserved, or emergency
+}
+
+//vehicleResponse describes a vehicle parked in the carpark
+type vehicleResponse struct {
+	Slot         int    `json:"slot"`
+	Floor        int    `json:"floor"`
+	Registration string `json:"registration,omitempty"`
+	Colour       string `json:"colour,omitempty"`
+	Type         string `json:"type,omitempty"`
+	Queued       bool   `json:"queued,omitempty"` //Whether the vehicle joined the waiting queue of a full carpark
+}
+
+//leaveResponse describes a vehicle which left the carpark
+type leaveResponse struct {
+	Slot         int               `json:"slot"`
+	Slots        []int             `json:"slots"` //Every slot freed by the vehicle
+	Registration string            `json:"registration"`
+	Duration     string            `json:"duration"`
+	Fee          string            `json:"fee"`
+	Admitted     []vehicleResponse `json:"admitted,omitempty"` //Waiting vehicles parked in the freed slots
+}
+
+//errorResponse is the body returned for a failed request
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+//errBadRequest reports a request body or path which could not be understood
+var errBadRequest = errors.New("Bad request")
+
+//newServer returns a handler serving the carpark endpoints:
+//
+//	POST   /carpark                create the carpark with {"floors": [...], "strategy": "..."}
+//	GET    /carpark                status of the vehicles parked
+//	POST   /vehicles               park a vehicle
+//	GET    /vehicles?colour=White  vehicles with the given colour
+//	GET    /vehicles/{reg}         vehicle with the given registration number
+//	DELETE /vehicles/{reg}         remove the vehicle with the given registration number
+//	DELETE /slots/{slot}           remove the vehicle parked at the slot
+func newServer(carpark *Carpark) http.Handler {
+	server := &server{carpark: carpark}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/carpark", server.handleCarpark)
+	mux.HandleFunc("/vehicles", server.handleVehicles)
+	mux.HandleFunc("/vehicles/", server.handleVehicle)
+	mux.HandleFunc("/slots/", server.handleSlot)
+	return mux
+}
+
+//serveCarpark serves the carpark on an address until the process is interrupted or terminated,
+//then finishes the requests in progress and saves any change to the carpark state not yet saved
+func serveCarpark(carpark *Carpark, address string) error {
+	httpServer := &http.Server{Addr: address, Handler: newServer(carpark)}
+	stopped := make(chan struct{})
+	go func() {
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+		<-signals
+		httpServer.Shutdown(context.Background())
+		close(stopped)
+	}()
+	if err := httpServer.ListenAndServe(); err != http.ErrServerClosed {
+		return err
+	}
+	<-stopped
+	return carpark.close()
+}
+
+//handleCarpark creates the carpark or reports its status
+func (server *server) handleCarpark(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost:
+		var req createRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, errBadRequest)
+			return
+		}
+		var strategy allocator
+		if req.Strategy != "" {
+			var err error
+			if strategy, err = parseStrategy(req.Strategy); err != nil {
+				writeError(w, err)
+				return
+			}
+		}
+		if err := server.carpark.init(strategy, req.Floors...); err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusCreated, createResponse{Slots: sum(req.Floors), Floors: len(req.Floors)})
+	case http.MethodGet:
+		if err := server.carpark.checkInit(); err != nil {
+			writeError(w, err)
+			return
+		}
+		vehicles := []vehicleResponse{}
+		for _, vehicle := range server.carpark.getStatus() {
+			vehicles = append(vehicles, server.describe(vehicle))
+		}
+		writeJSON(w, http.StatusOK, vehicles)
+	default:
+		writeMethodNotAllowed(w, "GET, POST")
+	}
+}
+
+//handleVehicles parks a vehicle or looks up vehicles by colour
+func (server *server) handleVehicles(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost:
+		var req parkRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, errBadRequest)
+			return
+		}
+		class, err := server.carpark.types().lookup(req.Type)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		vehicle := class.vehicle(req.Registration, req.Colour)
+		*vehicle.getPermits(), err = parseAttributes(req.Permits, permitNames, errUnknownPermit)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		slotNo, level, err := server.carpark.insertCar(vehicle)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		if slotNo == 0 {
+			writeJSON(w, http.StatusAccepted, vehicleResponse{Registration: *vehicle.getRegistration(), Queued: true})
+			return
+		}
+		writeJSON(w, http.StatusCreated, vehicleResponse{Slot: slotNo, Floor: level})
+	case http.MethodGet:
+		colour := r.URL.Query().Get("colour")
+		if colour == "" {
+			writeError(w, errBadRequest)
+			return
+		}
+		slots, registrations, err := server.carpark.getCarsWithColour(colour)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		var vehicles []vehicleResponse
+		for ii, slotNo := range slots {
+			vehicles = append(vehicles, vehicleResponse{Slot: slotNo, Floor: server.carpark.getLevel(slotNo), Registration: registrations[ii]})
+		}
+		writeJSON(w, http.StatusOK, vehicles)
+	default:
+		writeMethodNotAllowed(w, "GET, POST")
+	}
+}
+
+//handleVehicle looks up or removes a vehicle by registration number
+func (server *server) handleVehicle(w http.ResponseWriter, r *http.Request) {
+	registration := strings.TrimPrefix(r.URL.Path, "/vehicles/")
+	switch r.Method {
+	case http.MethodGet:
+		vehicle, err := server.carpark.getVehicle(registration)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, server.describe(vehicle))
+	case http.MethodDelete:
+		receipt, err := server.carpark.removeCarWithRegistration(registration)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, server.describeReceipt(receipt))
+	default:
+		writeMethodNotAllowed(w, "GET, DELETE")
+	}
+}
+
+//handleSlot removes the vehicle parked at a slot
+func (server *server) handleSlot(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		writeMethodNotAllowed(w, "DELETE")
+		return
+	}
+	slotNo, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/slots/"))
+	if err != nil {
+		writeError(w, errBadRequest)
+		return
+	}
+	receipt, err := server.carpark.removeCar(slotNo)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, server.describeReceipt(receipt))
+}
+
+//describe converts a parked vehicle into its JSON representation
+func (server *server) describe(vehicle Vehicle) vehicleResponse {
+	return vehicleResponse{
+		Slot:         *vehicle.getSlot(),
+		Floor:        server.carpark.getLevel(*vehicle.getSlot()),
+		Registration: *vehicle.getRegistration(),
+		Colour:       *vehicle.getColour(),
+		Type:         vehicle.getType(),
+	}
+}
+
+//describeReceipt converts the receipt of a vehicle leaving into its JSON representation
+func (server *server) describeReceipt(receipt *receipt) leaveResponse {
+	response := leaveResponse{
+		Slot:         *receipt.vehicle.getSlot(),
+		Slots:        receipt.slots,
+		Registration: *receipt.vehicle.getRegistration(),
+		Duration:     formatDuration(receipt.duration()),
+		Fee:          formatFee(receipt.fee),
+	}
+	for _, vehicle := range receipt.admitted {
+		response.Admitted = append(response.Admitted, server.describe(vehicle))
+	}
+	return response
+}
+
+//statusCode maps a carpark error onto an HTTP status code
+func statusCode(err error) int {
+	switch err.(type) {
+	case *relocationError:
+		return http.StatusConflict
+	case *unknownTypeError, *plateError:
+		return http.StatusBadRequest
+	}
+	switch err {
+	case errNotFound, errVehicleNotFound, errSlotNotFound:
+		return http.StatusNotFound
+	case errNotInitialized, errAlreadyInitialized, errFull, errDuplicate, errCannotCompact, errZoneOccupied, errAttributeOccupied,
+		errAlreadyReserved, errQueueNotEmpty, errShrinkReserved, errSlotOccupied, errSlotOpen:
+		return http.StatusConflict
+	case errBadRequest, errNoFloors, errEmptyFloor, errUnknownVehicle, errUnknownStrategy, errUnknownZone, errSlotRange,
+		errUnknownAttribute, errUnknownPermit, errReservationWindow, errUnknownQueue, errResize:
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+//writeError writes an error as a JSON body with the matching status code
+func writeError(w http.ResponseWriter, err error) {
+	writeJSON(w, statusCode(err), errorResponse{Error: err.Error()})
+}
+
+//writeMethodNotAllowed rejects a request made with an unsupported method
+func writeMethodNotAllowed(w http.ResponseWriter, allowed string) {
+	w.Header().Set("Allow", allowed)
+	writeJSON(w, http.StatusMethodNotAllowed, errorResponse{Error: "Method not allowed"})
+}
+
+//writeJSON writes a value as a JSON body
+func writeJSON(w http.ResponseWriter, code int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(value)
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func Test_server(t *testing.T) {
+	carpark := &Carpark{clock: fixedClock(testTime)}
+	handler := newServer(carpark)
+
+	//Requests are sent in order against the same carpark
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		body     string
+		wantCode int
+		wantBody string
+	}{
+		{name: "Status of uninitialized carpark",
+			method: "GET", path: "/carpark",
+			wantCode: http.StatusConflict,
+			wantBody: `{"error":"Carpark not initialized"}`,
+		},
+		{name: "Create carpark",
+			method: "POST", path: "/carpark", body: `{"floors": [2, 2]}`,
+			wantCode: http.StatusCreated,
+			wantBody: `{"slots":4,"floors":2}`,
+		},
+		{name: "Create carpark twice",
+			method: "POST", path: "/carpark", body: `{"floors": [2]}`,
+			wantCode: http.StatusConflict,
+			wantBody: `{"error":"Carpark already initialized"}`,
+		},
+		{name: "Park motorcycle",
+			method: "POST", path: "/vehicles", body: `{"registration": "KA-01-HH-1234", "colour": "White", "type": "motorcycle"}`,
+			wantCode: http.StatusCreated,
+			wantBody: `{"slot":1,"floor":1}`,
+		},
+		{name: "Park car",
+			method: "POST", path: "/vehicles", body: `{"registration": "KA-01-HH-9999", "colour": "White", "type": "car"}`,
+			wantCode: http.StatusCreated,
+			wantBody: `{"slot":3,"floor":2}`,
+		},
+		{name: "Park unknown vehicle type",
+			method: "POST", path: "/vehicles", body: `{"registration": "KA-01-HH-7777", "colour": "Red", "type": "tram"}`,
+			wantCode: http.StatusBadRequest,
+			wantBody: `{"error":"Unknown vehicle type tram, expected one of bus, car, motorcycle"}`,
+		},
+		{name: "Park with malformed body",
+			method: "POST", path: "/vehicles", body: `{"registration":`,
+			wantCode: http.StatusBadRequest,
+			wantBody: `{"error":"Bad request"}`,
+		},
+		{name: "Park bus in full carpark",
+			method: "POST", path: "/vehicles", body: `{"registration": "KA-01-HH-7777", "colour": "Red", "type": "bus"}`,
+			wantCode: http.StatusConflict,
+			wantBody: `{"error":"Sorry, parking lot is full"}`,
+		},
+		{name: "Lookup by colour",
+			method: "GET", path: "/vehicles?colour=White",
+			wantCode: http.StatusOK,
+			wantBody: `[{"slot":1,"floor":1,"registration":"KA-01-HH-1234"},{"slot":3,"floor":2,"registration":"KA-01-HH-9999"}]`,
+		},
+		{name: "Lookup by missing colour",
+			method: "GET", path: "/vehicles?colour=Green",
+			wantCode: http.StatusNotFound,
+			wantBody: `{"error":"Not found"}`,
+		},
+		{name: "Lookup by registration",
+			method: "GET", path: "/vehicles/KA-01-HH-9999",
+			wantCode: http.StatusOK,
+			wantBody: `{"slot":3,"floor":2,"registration":"KA-01-HH-9999","colour":"White","type":"Car"}`,
+		},
+		{name: "Leave",
+			method: "DELETE", path: "/slots/1",
+			wantCode: http.StatusOK,
+			wantBody: `{"slot":1,"slots":[1],"registration":"KA-01-HH-1234","duration":"0h00m","fee":"0.00"}`,
+		},
+		{name: "Leave empty slot",
+			method: "DELETE", path: "/slots/1",
+			wantCode: http.StatusNotFound,
+			wantBody: `{"error":"Vehicle non-existent in carpark"}`,
+		},
+		{name: "Leave invalid slot",
+			method: "DELETE", path: "/slots/first",
+			wantCode: http.StatusBadRequest,
+			wantBody: `{"error":"Bad request"}`,
+		},
+		{name: "Status",
+			method: "GET", path: "/carpark",
+			wantCode: http.StatusOK,
+			wantBody: `[{"slot":3,"floor":2,"registration":"KA-01-HH-9999","colour":"White","type":"Car"}]`,
+		},
+		{name: "Leave by registration",
+			method: "DELETE", path: "/vehicles/KA-01-HH-9999",
+			wantCode: http.StatusOK,
+			wantBody: `{"slot":3,"slots":[3,4],"registration":"KA-01-HH-9999","duration":"0h00m","fee":"0.00"}`,
+		},
+		{name: "Leave by registration not in carpark",
+			method: "DELETE", path: "/vehicles/KA-01-HH-9999",
+			wantCode: http.StatusNotFound,
+			wantBody: `{"error":"Not found"}`,
+		},
+		{name: "Unsupported method",
+			method: "PUT", path: "/carpark",
+			wantCode: http.StatusMethodNotAllowed,
+			wantBody: `{"error":"Method not allowed"}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != tt.wantCode {
+				t.Errorf("%v %v code = %v, want %v", tt.method, tt.path, rec.Code, tt.wantCode)
+			}
+			if got := strings.TrimSpace(rec.Body.String()); got != tt.wantBody {
+				t.Errorf("%v %v body = %v, want %v", tt.method, tt.path, got, tt.wantBody)
+			}
+		})
+	}
+}
+
+func Test_serverQueued(t *testing.T) {
+	carpark := &Carpark{clock: fixedClock(testTime)}
+	carpark.init(nil, 2)
+	carpark.insertCar(defaultVehicleTypes.newVehicle("car", "KA-01-HH-9999", "White"))
+	carpark.setQueue(queueFIFO)
+
+	req := httptest.NewRequest("POST", "/vehicles", strings.NewReader(`{"registration": "KA-01-HH-7777", "colour": "Red", "type": "car"}`))
+	rec := httptest.NewRecorder()
+	newServer(carpark).ServeHTTP(rec, req)
+	if rec.Code != http.StatusAccepted {
+		t.Errorf("POST /vehicles code = %v, want %v", rec.Code, http.StatusAccepted)
+	}
+	if got, want := strings.TrimSpace(rec.Body.String()), `{"slot":0,"floor":0,"registration":"KA-01-HH-7777","queued":true}`; got != want {
+		t.Errorf("POST /vehicles body = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,308 @@
+package main
+
+import (
+	"container/list"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"strings"
+	"time"
+)
+
+//carparkState is the saved form of everything needed to rebuild a carpark
+type carparkState struct {
+	Strategy     string              `json:"strategy"`
+	Floors       []floorState        `json:"floors"`
+	Vehicles     []vehicleState      `json:"vehicles"`
+	Zones        map[int]string      `json:"zones,omitempty"`        //Type of each slot which is not a general slot
+	Overflow     map[string][]string `json:"overflow,omitempty"`     //Zones into which each vehicle type overflows
+	Attributes   map[int][]string    `json:"attributes,omitempty"`   //Attributes of each slot carrying any
+	Reservations []reservationState  `json:"reservations,omitempty"` //Slots held for expected vehicles
+	Queue        string              `json:"queue,omitempty"`        //Order of the waiting queue, empty when vehicles are turned away
+	Waiting      []vehicleState      `json:"waiting,omitempty"`      //Vehicles in the waiting queue, in the order they would be admitted
+}
+
+//floorState is the saved form of a floor
+type floorState struct {
+	Level       int            `json:"level"`
+	FirstSlot   int            `json:"first_slot"`
+	HighestSlot int            `json:"highest_slot"`
+	MaxSlot     int            `json:"max_slot"`
+	EmptySlots  []int          `json:"empty_slots"`
+	Closed      map[int]string `json:"closed,omitempty"` //Reason each slot out of service was closed
+}
+
+//vehicleState is the saved form of a parked vehicle
+type vehicleState struct {
+	Type         string    `json:"type"`
+	Registration string    `json:"registration"`
+	Colour       string    `json:"colour"`
+	Slot         int       `json:"slot"`
+	Arrival      time.Time `json:"arrival"`
+	Permits      []string  `json:"permits,omitempty"`
+}
+
+//reservationState is the saved form of a reservation
+type reservationState struct {
+	Registration string    `json:"registration"`
+	Type         string    `json:"type"`
+	Slot         int       `json:"slot"`
+	From         time.Time `json:"from"`
+	To           time.Time `json:"to"`
+}
+
+//stateStore saves the carpark state to a file
+type stateStore struct {
+	fileName string        //File holding the saved state
+	interval time.Duration //Time between saves, or 0 to save after every change
+	dirty    bool          //Whether the state changed since it was last saved
+	stop     func()        //Stops the periodic saves, nil when saves follow every change
+}
+
+//Restore the carpark from a state file if one exists, and keep saving the state to that file
+//after every change, or every 'interval' when it is positive
+func (carpark *Carpark) persist(fileName string, interval time.Duration) error {
+	carpark.mu.Lock()
+	defer carpark.mu.Unlock()
+
+	data, err := ioutil.ReadFile(fileName)
+	switch {
+	case os.IsNotExist(err): //Start afresh
+	case err != nil:
+		return err
+	default:
+		var state carparkState
+		if err := json.Unmarshal(data, &state); err != nil {
+			return fmt.Errorf("Invalid state file %v: %v", fileName, err)
+		}
+		if err := carpark.restore(&state); err != nil {
+			return fmt.Errorf("Invalid state file %v: %v", fileName, err)
+		}
+	}
+
+	carpark.store = &stateStore{fileName: fileName, interval: interval}
+	if interval > 0 {
+		ticker := time.NewTicker(interval)
+		done := make(chan struct{})
+		go func() {
+			for {
+				select {
+				case <-ticker.C:
+					if err := carpark.flush(); err != nil {
+						log.Printf("Failed to save carpark state: %v", err)
+					}
+				case <-done:
+					return
+				}
+			}
+		}()
+		carpark.store.stop = func() {
+			ticker.Stop()
+			close(done)
+		}
+	}
+	return nil
+}
+
+//Save the carpark state if it changed since it was last saved
+func (carpark *Carpark) flush() error {
+	carpark.mu.Lock()
+	defer carpark.mu.Unlock()
+
+	if carpark.store == nil || !carpark.store.dirty {
+		return nil
+	}
+	return carpark.save()
+}
+
+//Stop the periodic saves of the carpark state and save any change not yet saved
+func (carpark *Carpark) close() error {
+	carpark.mu.Lock()
+	defer carpark.mu.Unlock()
+
+	if carpark.store == nil {
+		return nil
+	}
+	if carpark.store.stop != nil {
+		carpark.store.stop()
+		carpark.store.stop = nil
+	}
+	if !carpark.store.dirty {
+		return nil
+	}
+	return carpark.save()
+}
+
+//Record a change to the carpark state, saving it immediately unless saves are periodic
+func (carpark *Carpark) changed() {
+	if carpark.store == nil {
+		return
+	}
+	carpark.store.dirty = true
+	if carpark.store.interval > 0 {
+		return
+	}
+	if err := carpark.save(); err != nil {
+		log.Printf("Failed to save carpark state: %v", err)
+	}
+}
+
+//Write the carpark state to the state file, replacing the previous state atomically
+func (carpark *Carpark) save() error {
+	data, err := json.MarshalIndent(carpark.snapshot(), "", "  ")
+	if err != nil {
+		return err
+	}
+	tmpName := carpark.store.fileName + ".tmp"
+	if err := ioutil.WriteFile(tmpName, data, 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpName, carpark.store.fileName); err != nil {
+		return err
+	}
+	carpark.store.dirty = false
+	return nil
+}
+
+//Capture the carpark state
+func (carpark *Carpark) snapshot() *carparkState {
+	state := &carparkState{Strategy: carpark.allocator().String(), Zones: carpark.zones, Overflow: carpark.overflow}
+	for slotNo, attributes := range carpark.attributes {
+		if state.Attributes == nil {
+			state.Attributes = make(map[int][]string)
+		}
+		state.Attributes[slotNo] = attributes.names(attributeNames)
+	}
+	for _, floor := range carpark.floors {
+		floorState := floorState{
+			Level:       floor.level,
+			FirstSlot:   floor.firstSlot,
+			HighestSlot: floor.highestSlot,
+			MaxSlot:     floor.maxSlot,
+			Closed:      floor.closed,
+		}
+		for e := floor.emptySlots.Front(); e != nil; e = e.Next() {
+			floorState.EmptySlots = append(floorState.EmptySlots, e.Value.(int))
+		}
+		state.Floors = append(state.Floors, floorState)
+	}
+	for _, vehicle := range carpark.vehicles() {
+		state.Vehicles = append(state.Vehicles, saveVehicle(vehicle))
+	}
+	if carpark.queue != nil {
+		state.Queue = carpark.queue.mode
+		for _, vehicle := range carpark.queue.vehicles() {
+			state.Waiting = append(state.Waiting, saveVehicle(vehicle))
+		}
+	}
+	for _, reservation := range carpark.reservations {
+		state.Reservations = append(state.Reservations, reservationState{
+			Registration: reservation.registration,
+			Type:         reservation.vehicleType,
+			Slot:         reservation.slot,
+			From:         reservation.from,
+			To:           reservation.to,
+		})
+	}
+	return state
+}
+
+//saveVehicle captures the state of a vehicle
+func saveVehicle(vehicle Vehicle) vehicleState {
+	return vehicleState{
+		Type:         strings.ToLower(vehicle.getType()),
+		Registration: *vehicle.getRegistration(),
+		Colour:       *vehicle.getColour(),
+		Slot:         *vehicle.getSlot(),
+		Arrival:      *vehicle.getArrival(),
+		Permits:      vehicle.getPermits().names(permitNames),
+	}
+}
+
+//loadVehicle rebuilds a vehicle of one of the registered types from its saved state, leaving it to be placed
+func loadVehicle(vehicleState vehicleState, registry vehicleRegistry) (Vehicle, error) {
+	vehicle := registry.newVehicle(vehicleState.Type, vehicleState.Registration, vehicleState.Colour)
+	if vehicle == nil {
+		return nil, fmt.Errorf("unknown vehicle type %q", vehicleState.Type)
+	}
+	permits, err := parseAttributes(vehicleState.Permits, permitNames, errUnknownPermit)
+	if err != nil {
+		return nil, err
+	}
+	*vehicle.getPermits() = permits
+	*vehicle.getArrival() = vehicleState.Arrival
+	return vehicle, nil
+}
+
+//Rebuild the carpark from a saved state
+func (carpark *Carpark) restore(state *carparkState) error {
+	if len(state.Floors) == 0 { //State saved before the carpark was created
+		return nil
+	}
+	strategy, err := parseStrategy(state.Strategy)
+	if err != nil {
+		return err
+	}
+	carpark.strategy = strategy
+	carpark.Map = make(map[int]Vehicle)
+	carpark.registrations = make(map[string]int)
+	carpark.colours = make(map[string][]int)
+	carpark.floors = nil
+	carpark.zones = state.Zones
+	carpark.overflow = state.Overflow
+	carpark.attributes = nil
+	carpark.reservations = nil
+	for slotNo, names := range state.Attributes {
+		attributes, err := parseAttributes(names, attributeNames, errUnknownAttribute)
+		if err != nil {
+			return err
+		}
+		carpark.mark(attributes, slotNo, slotNo, true)
+	}
+	for _, floorState := range state.Floors {
+		floor := &floor{
+			level:       floorState.Level,
+			firstSlot:   floorState.FirstSlot,
+			highestSlot: floorState.HighestSlot,
+			maxSlot:     floorState.MaxSlot,
+			emptySlots:  list.New(),
+			closed:      floorState.Closed,
+		}
+		for _, slotNo := range floorState.EmptySlots {
+			floor.emptySlots.PushBack(slotNo)
+		}
+		carpark.floors = append(carpark.floors, floor)
+	}
+	for _, vehicleState := range state.Vehicles {
+		vehicle, err := loadVehicle(vehicleState, carpark.types())
+		if err != nil {
+			return err
+		}
+		carpark.place(vehicle, vehicleState.Slot, vehicleState.Arrival)
+	}
+	carpark.queue = nil
+	if state.Queue != "" {
+		carpark.queueMode(state.Queue)
+	}
+	for _, vehicleState := range state.Waiting {
+		vehicle, err := loadVehicle(vehicleState, carpark.types())
+		if err != nil {
+			return err
+		}
+		if carpark.queue == nil {
+			return fmt.Errorf("vehicle %v waiting without a queue", vehicleState.Registration)
+		}
+		carpark.queue.push(vehicle)
+	}
+	for _, reservationState := range state.Reservations {
+		vehicle := carpark.types().newVehicle(reservationState.Type, reservationState.Registration, "")
+		if vehicle == nil {
+			return fmt.Errorf("unknown vehicle type %q", reservationState.Type)
+		}
+		carpark.hold(&reservation{registration: reservationState.Registration, vehicleType: reservationState.Type,
+			slot: reservationState.Slot, slots: vehicle.getSlotsNeeded(), from: reservationState.From, to: reservationState.To})
+	}
+	return nil
+}
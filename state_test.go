@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCarpark_persist(t *testing.T) {
+	dir, err := ioutil.TempDir("", "state")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	tests := []struct {
+		name     string
+		interval time.Duration
+		flush    bool
+		wantSave bool
+	}{
+		{name: "Save after every change", interval: 0, flush: false, wantSave: true},
+		{name: "Periodic save before flush", interval: time.Hour, flush: false, wantSave: false},
+		{name: "Periodic save after flush", interval: time.Hour, flush: true, wantSave: true},
+	}
+	for ii, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fileName := filepath.Join(dir, fmt.Sprintf("state%v.json", ii))
+
+			//Operate a carpark until it goes down
+			carpark := &Carpark{clock: fixedClock(testTime)}
+			if err := carpark.persist(fileName, tt.interval); err != nil {
+				t.Fatal(err)
+			}
+			carpark.init(bestFit{}, 2, 3)
+			carpark.setZone("bus", 3, 5)
+			carpark.setOverflow("motorcycle", []string{"bus"})
+			carpark.setAttribute("ev_charger", 1, 2)
+			carpark.setAttribute("accessible", 2, 2)
+			for _, vehicle := range []Vehicle{values().vehicle1, values().vehicle2,
+				defaultVehicleTypes.newVehicle("car", "KA-01-HH-9999", "White"), defaultVehicleTypes.newVehicle("bus", "KA-01-BB-0001", "Black")} {
+				carpark.insertCar(vehicle)
+			}
+			carpark.removeCar(1)
+			carpark.reserve("KA-01-RR-0001", "motorcycle", testTime.Add(time.Hour), testTime.Add(2*time.Hour))
+			if tt.flush {
+				if err := carpark.flush(); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			//Restore a fresh carpark from the saved state
+			restored := &Carpark{}
+			if err := restored.persist(fileName, tt.interval); err != nil {
+				t.Fatal(err)
+			}
+			if !tt.wantSave {
+				if restored.checkInit() == nil {
+					t.Errorf("Carpark.persist() restored state which should not have been saved yet")
+				}
+				return
+			}
+			compareCarpark(t, restored, carpark)
+		})
+	}
+}
+
+func TestCarpark_persistInvalidState(t *testing.T) {
+	dir, err := ioutil.TempDir("", "state")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	fileName := filepath.Join(dir, "state.json")
+	if err := ioutil.WriteFile(fileName, []byte(`{"floors": [`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := (&Carpark{}).persist(fileName, 0); err == nil {
+		t.Errorf("Carpark.persist() error = nil, want error for corrupt state file")
+	}
+}
+
+func TestCarpark_close(t *testing.T) {
+	dir, err := ioutil.TempDir("", "state")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	fileName := filepath.Join(dir, "state.json")
+	carpark := &Carpark{clock: fixedClock(testTime)}
+	if err := carpark.persist(fileName, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	carpark.init(bestFit{}, 4)
+	carpark.insertCar(values().vehicle1)
+	if err := carpark.close(); err != nil {
+		t.Fatalf("Carpark.close() error = %v", err)
+	}
+	if carpark.store.stop != nil {
+		t.Errorf("Carpark.close() left the periodic saves running")
+	}
+	if err := carpark.close(); err != nil {
+		t.Errorf("Carpark.close() error = %v on closing twice", err)
+	}
+
+	restored := &Carpark{}
+	if err := restored.persist(fileName, 0); err != nil {
+		t.Fatal(err)
+	}
+	compareCarpark(t, restored, carpark)
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+//tariffPlan represents the parking charges of every vehicle type and the calendar they follow
+type tariffPlan struct {
+	Holidays []string          `json:"holidays"` //Dates charged at weekend rates, formatted as 2006-01-02
+	Tariffs  map[string]tariff `json:"tariffs"`  //Parking charges keyed by vehicle type
+}
+
+//tariff represents the parking charges of a vehicle type, in cents
+type tariff struct {
+	FirstHour    int        `json:"first_hour"`    //Charge for the first hour or part thereof
+	Hourly       int        `json:"hourly"`        //Off-peak charge for every subsequent hour or part thereof
+	DailyCap     int        `json:"daily_cap"`     //Maximum charge for every 24 hours of parking, 0 for no cap
+	GraceMinutes int        `json:"grace_minutes"` //Parking no longer than the grace period is free
+	Peak         []band     `json:"peak"`          //Time bands charged at peak rates on weekdays
+	Weekend      int        `json:"weekend"`       //Hourly charge on weekends and holidays, 0 to use the off-peak charge
+	Overnight    *overnight `json:"overnight"`     //Flat fee replacing hourly charges overnight, nil for none
+}
+
+//band represents a time of day charged at its own hourly rate
+type band struct {
+	From   string `json:"from"`   //Start of the band, formatted as 15:04
+	To     string `json:"to"`     //End of the band, formatted as 15:04
+	Hourly int    `json:"hourly"` //Charge for every hour starting within the band
+}
+
+//overnight represents a flat fee covering every hour starting within a nightly window
+type overnight struct {
+	From string `json:"from"` //Start of the window, formatted as 15:04
+	To   string `json:"to"`   //End of the window on the following morning, formatted as 15:04
+	Fee  int    `json:"fee"`  //Charge for each night
+}
+
+//defaultTariffs holds the parking charges of each vehicle type
+var defaultTariffs = &tariffPlan{Tariffs: map[string]tariff{
+	"Motorcycle": {FirstHour: 100, Hourly: 50, DailyCap: 500, GraceMinutes: 10},
+	"Car":        {FirstHour: 200, Hourly: 150, DailyCap: 1500, GraceMinutes: 10},
+	"Bus":        {FirstHour: 500, Hourly: 400, DailyCap: 4000, GraceMinutes: 10},
+}}
+
+//loadTariffs reads a tariff plan from a JSON file, falling back to the default tariff of any vehicle type it omits
+func loadTariffs(fileName string) (*tariffPlan, error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var plan tariffPlan
+	if err := json.NewDecoder(file).Decode(&plan); err != nil {
+		return nil, fmt.Errorf("Invalid tariff file %v: %v", fileName, err)
+	}
+	if err := plan.validate(); err != nil {
+		return nil, fmt.Errorf("Invalid tariff file %v: %v", fileName, err)
+	}
+	if plan.Tariffs == nil {
+		plan.Tariffs = make(map[string]tariff)
+	}
+	for vehicleType, tariff := range defaultTariffs.Tariffs {
+		if _, ok := plan.Tariffs[vehicleType]; !ok {
+			plan.Tariffs[vehicleType] = tariff
+		}
+	}
+	return &plan, nil
+}
+
+//validate checks the dates and times of day used in the tariff plan
+func (plan *tariffPlan) validate() error {
+	for _, holiday := range plan.Holidays {
+		if _, err := time.Parse("2006-01-02", holiday); err != nil {
+			return fmt.Errorf("holiday %q is not a date", holiday)
+		}
+	}
+	for vehicleType, tariff := range plan.Tariffs {
+		var times []string
+		for _, band := range tariff.Peak {
+			times = append(times, band.From, band.To)
+		}
+		if tariff.Overnight != nil {
+			times = append(times, tariff.Overnight.From, tariff.Overnight.To)
+		}
+		for _, t := range times {
+			if _, err := minuteOfDay(t); err != nil {
+				return fmt.Errorf("%v tariff: %v", vehicleType, err)
+			}
+		}
+	}
+	return nil
+}
+
+//fee computes the charge for a vehicle type parked from arrival until departure
+func (plan *tariffPlan) fee(vehicleType string, arrival time.Time, departure time.Time) int {
+	tariff, ok := plan.Tariffs[vehicleType]
+	if !ok || departure.Sub(arrival) <= time.Duration(tariff.GraceMinutes)*time.Minute {
+		return 0
+	}
+	total := 0
+	dayCharge := 0
+	nights := make(map[string]bool) //Nights already charged the overnight fee
+	for hour := 0; arrival.Add(time.Duration(hour) * time.Hour).Before(departure); hour++ {
+		if hour%24 == 0 { //Apply the daily cap to every 24 hours of parking
+			total += tariff.cap(dayCharge)
+			dayCharge = 0
+		}
+		start := arrival.Add(time.Duration(hour) * time.Hour)
+		switch night, ok := tariff.night(start); {
+		case ok:
+			if !nights[night] {
+				nights[night] = true
+				dayCharge += tariff.Overnight.Fee
+			}
+		case hour == 0:
+			dayCharge += tariff.FirstHour
+		default:
+			dayCharge += tariff.hourly(start, plan.isWeekend(start))
+		}
+	}
+	return total + tariff.cap(dayCharge)
+}
+
+//isWeekend checks whether a time falls on a weekend or holiday
+func (plan *tariffPlan) isWeekend(t time.Time) bool {
+	if t.Weekday() == time.Saturday || t.Weekday() == time.Sunday {
+		return true
+	}
+	date := t.Format("2006-01-02")
+	for _, holiday := range plan.Holidays {
+		if holiday == date {
+			return true
+		}
+	}
+	return false
+}
+
+//hourly returns the charge for an hour starting at the given time
+func (tariff tariff) hourly(start time.Time, weekend bool) int {
+	if weekend {
+		if tariff.Weekend > 0 {
+			return tariff.Weekend
+		}
+		return tariff.Hourly
+	}
+	for _, band := range tariff.Peak {
+		if inWindow(start, band.From, band.To) {
+			return band.Hourly
+		}
+	}
+	return tariff.Hourly
+}
+
+//night identifies the night whose overnight window contains the given time
+func (tariff tariff) night(t time.Time) (string, bool) {
+	if tariff.Overnight == nil || !inWindow(t, tariff.Overnight.From, tariff.Overnight.To) {
+		return "", false
+	}
+	from, _ := minuteOfDay(tariff.Overnight.From)
+	if t.Hour()*60+t.Minute() < from { //Morning part of a window which started the previous evening
+		t = t.AddDate(0, 0, -1)
+	}
+	return t.Format("2006-01-02"), true
+}
+
+//cap limits a charge to the daily cap
+func (tariff tariff) cap(charge int) int {
+	if tariff.DailyCap > 0 && charge > tariff.DailyCap {
+		return tariff.DailyCap
+	}
+	return charge
+}
+
+//inWindow checks whether the time of day lies within [from, to), where a window may wrap past midnight
+func inWindow(t time.Time, from string, to string) bool {
+	fromMinute, _ := minuteOfDay(from)
+	toMinute, _ := minuteOfDay(to)
+	minute := t.Hour()*60 + t.Minute()
+	if fromMinute <= toMinute {
+		return minute >= fromMinute && minute < toMinute
+	}
+	return minute >= fromMinute || minute < toMinute
+}
+
+//minuteOfDay converts a time of day formatted as 15:04 into minutes after midnight
+func minuteOfDay(clock string) (int, error) {
+	t, err := time.Parse("15:04", clock)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a time of day", clock)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+//receipt represents the charges for a vehicle parked in the carpark
+type receipt struct {
+	vehicle   Vehicle   //Vehicle being charged
+	slots     []int     //Slots occupied by the vehicle
+	arrival   time.Time //Time at which the vehicle was parked
+	departure time.Time //Time up to which the vehicle is charged
+	fee       int       //Parking charge in cents
+	admitted  []Vehicle //Waiting vehicles parked in the freed slots
+}
+
+//newReceipt creates the receipt for a vehicle parked from its arrival until 'departure'
+func newReceipt(vehicle Vehicle, departure time.Time) *receipt {
+	receipt := &receipt{vehicle: vehicle, arrival: *vehicle.getArrival(), departure: departure}
+	for slotNo := *vehicle.getSlot(); slotNo < *vehicle.getSlot()+vehicle.getSlotsNeeded(); slotNo++ {
+		receipt.slots = append(receipt.slots, slotNo)
+	}
+	return receipt
+}
+
+//duration returns how long the vehicle was parked
+func (receipt *receipt) duration() time.Duration {
+	return receipt.departure.Sub(receipt.arrival)
+}
+
+//String formats the parking duration and fee
+func (receipt *receipt) String() string {
+	return fmt.Sprintf("Duration: %v, Fee: %v", formatDuration(receipt.duration()), formatFee(receipt.fee))
+}
+
+//formatDuration formats a duration in hours and minutes
+func formatDuration(duration time.Duration) string {
+	minutes := int(duration / time.Minute)
+	return fmt.Sprintf("%dh%02dm", minutes/60, minutes%60)
+}
+
+//formatFee formats an amount in cents
+func formatFee(cents int) string {
+	return fmt.Sprintf("%d.%02d", cents/100, cents%100)
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func Test_tariffPlan_fee(t *testing.T) {
+	plan := &tariffPlan{
+		Holidays: []string{"2018-11-06"},
+		Tariffs: map[string]tariff{
+			"Car": {FirstHour: 200, Hourly: 100, GraceMinutes: 10, Weekend: 50,
+				Peak:      []band{{From: "07:00", To: "10:00", Hourly: 300}},
+				Overnight: &overnight{From: "22:00", To: "07:00", Fee: 500},
+			},
+		},
+	}
+	//2018-11-01 is a Thursday
+	at := func(day int, hour int, minute int) time.Time {
+		return time.Date(2018, 11, day, hour, minute, 0, 0, time.UTC)
+	}
+	tests := []struct {
+		name        string
+		plan        *tariffPlan
+		vehicleType string
+		arrival     time.Time
+		departure   time.Time
+		want        int
+	}{
+		{name: "Within grace period", plan: defaultTariffs, vehicleType: "Car", arrival: at(1, 9, 0), departure: at(1, 9, 10), want: 0},
+		{name: "First hour", plan: defaultTariffs, vehicleType: "Car", arrival: at(1, 9, 0), departure: at(1, 9, 11), want: 200},
+		{name: "Part of second hour", plan: defaultTariffs, vehicleType: "Car", arrival: at(1, 9, 0), departure: at(1, 10, 1), want: 350},
+		{name: "Daily cap", plan: defaultTariffs, vehicleType: "Car", arrival: at(1, 9, 0), departure: at(2, 5, 0), want: 1500},
+		{name: "Second day", plan: defaultTariffs, vehicleType: "Car", arrival: at(1, 9, 0), departure: at(2, 11, 0), want: 1800},
+		{name: "Unknown vehicle type", plan: defaultTariffs, vehicleType: "Tram", arrival: at(1, 9, 0), departure: at(1, 11, 0), want: 0},
+		{name: "Weekday off-peak", plan: plan, vehicleType: "Car", arrival: at(1, 11, 0), departure: at(1, 14, 0), want: 400},
+		{name: "Weekday peak", plan: plan, vehicleType: "Car", arrival: at(1, 6, 0), departure: at(1, 9, 0), want: 1100},
+		{name: "Weekend", plan: plan, vehicleType: "Car", arrival: at(3, 12, 0), departure: at(3, 15, 0), want: 300},
+		{name: "Holiday", plan: plan, vehicleType: "Car", arrival: at(6, 12, 0), departure: at(6, 15, 0), want: 300},
+		{name: "Overnight", plan: plan, vehicleType: "Car", arrival: at(1, 21, 0), departure: at(2, 8, 30), want: 1300},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.plan.fee(tt.vehicleType, tt.arrival, tt.departure); got != tt.want {
+				t.Errorf("tariffPlan.fee() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_loadTariffs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tariffs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	tests := []struct {
+		name    string
+		content string
+		want    *tariffPlan
+		wantErr bool
+	}{
+		{name: "Override one vehicle type",
+			content: `{"holidays": ["2018-12-25"], "tariffs": {"Car": {"first_hour": 300, "hourly": 200}}}`,
+			want: &tariffPlan{Holidays: []string{"2018-12-25"}, Tariffs: map[string]tariff{
+				"Motorcycle": defaultTariffs.Tariffs["Motorcycle"],
+				"Car":        {FirstHour: 300, Hourly: 200},
+				"Bus":        defaultTariffs.Tariffs["Bus"],
+			}},
+			wantErr: false,
+		},
+		{name: "Invalid holiday",
+			content: `{"holidays": ["Christmas"]}`,
+			wantErr: true,
+		},
+		{name: "Invalid time band",
+			content: `{"tariffs": {"Car": {"peak": [{"from": "7am", "to": "10:00"}]}}}`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fileName := filepath.Join(dir, "tariffs.json")
+			if err := ioutil.WriteFile(fileName, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			got, err := loadTariffs(fileName)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("loadTariffs() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("loadTariffs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_receipt_String(t *testing.T) {
+	r := &receipt{arrival: testTime, departure: testTime.Add(125 * time.Minute), fee: 650}
+	if got, want := r.String(), "Duration: 2h05m, Fee: 6.50"; got != want {
+		t.Errorf("receipt.String() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,9 @@
+package main
+
+import "syscall"
+
+//Requests reading and setting the terminal attributes on macOS
+const (
+	ioctlGetTermios = syscall.TIOCGETA
+	ioctlSetTermios = syscall.TIOCSETA
+)
@@ -0,0 +1,9 @@
+package main
+
+import "syscall"
+
+//Requests reading and setting the terminal attributes on Linux
+const (
+	ioctlGetTermios = syscall.TCGETS
+	ioctlSetTermios = syscall.TCSETS
+)
@@ -0,0 +1,8 @@
+//go:build !linux && !darwin
+
+package main
+
+//makeRaw reports every input as not a terminal, so that commands are read line by line without editing
+func makeRaw(fd uintptr) (func(), error) {
+	return nil, errNotTerminal
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+)
+
+//syntaxError reports where a command could not be read
+type syntaxError struct {
+	line   int    //Line of input on which the command starts
+	token  int    //Position of the token within the command, counting from 1
+	reason string //What is wrong with the token
+}
+
+func (err *syntaxError) Error() string {
+	return fmt.Sprintf("Line %v, token %v: %v", err.line, err.token, err.reason)
+}
+
+//tokenize splits a line into tokens separated by spaces or tabs, where double or single quotes keep spaces
+//within a token, a backslash escapes the next character, and # starts a comment running to the end of the line.
+//A backslash ending the line reports that the command continues on the next line
+func tokenize(line string) (tokens []string, continued bool, err error) {
+	var token []rune
+	inToken := false
+	var quote rune //Quote character of the quoted text being read, 0 when outside quotes
+	end := func() {
+		if inToken {
+			tokens = append(tokens, string(token))
+		}
+		token, inToken = nil, false
+	}
+	runes := []rune(line)
+	for ii := 0; ii < len(runes); ii++ {
+		r := runes[ii]
+		switch {
+		case r == '\\' && quote != '\'':
+			if ii == len(runes)-1 {
+				if quote != 0 {
+					return nil, false, &syntaxError{token: len(tokens) + 1, reason: "unterminated quote"}
+				}
+				end()
+				return tokens, true, nil
+			}
+			ii++
+			token, inToken = append(token, runes[ii]), true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				token = append(token, r)
+			}
+		case r == '"' || r == '\'':
+			quote, inToken = r, true
+		case unicode.IsSpace(r):
+			end()
+		case r == '#' && !inToken:
+			return tokens, false, nil
+		default:
+			token, inToken = append(token, r), true
+		}
+	}
+	if quote != 0 {
+		return nil, false, &syntaxError{token: len(tokens) + 1, reason: "unterminated quote"}
+	}
+	end()
+	return tokens, false, nil
+}
+
+//commandReader reads commands from input, one to a line unless the line is continued,
+//skipping blank lines and comments
+type commandReader struct {
+	scanner *bufio.Scanner
+	line    int //Number of the last line read
+	start   int //Line on which the last command read starts
+}
+
+//newCommandReader reads commands from the lines of a scanner
+func newCommandReader(scanner *bufio.Scanner) *commandReader {
+	return &commandReader{scanner: scanner}
+}
+
+//next returns the tokens of the next command with its verb in lower case, or io.EOF at the end of input
+func (reader *commandReader) next() ([]string, error) {
+	var tokens []string
+	continued := false
+	for reader.scanner.Scan() {
+		reader.line++
+		if !continued {
+			reader.start = reader.line
+		}
+		more, again, err := tokenize(reader.scanner.Text())
+		if err != nil {
+			err.(*syntaxError).line = reader.start
+			err.(*syntaxError).token += len(tokens)
+			return nil, err
+		}
+		tokens, continued = append(tokens, more...), again
+		if !continued && len(tokens) > 0 {
+			tokens[0] = strings.ToLower(tokens[0])
+			return tokens, nil
+		}
+	}
+	if err := reader.scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(tokens) > 0 {
+		tokens[0] = strings.ToLower(tokens[0])
+		return tokens, nil
+	}
+	return nil, io.EOF
+}
+
+//errorAt reports a token of the last command read which could not be used
+func (reader *commandReader) errorAt(token int, reason string) error {
+	return &syntaxError{line: reader.start, token: token + 1, reason: reason}
+}
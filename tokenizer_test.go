@@ -0,0 +1,69 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func Test_tokenize(t *testing.T) {
+	tests := []struct {
+		name          string
+		line          string
+		wantTokens    []string
+		wantContinued bool
+		wantErr       string
+	}{
+		{name: "Runs of spaces and tabs", line: " park\tKA-01-HH-1234  White car ", wantTokens: []string{"park", "KA-01-HH-1234", "White", "car"}},
+		{name: "Blank line", line: "  \t", wantTokens: nil},
+		{name: "Double quotes", line: `park KA-01-HH-1234 "Dark Blue" car`, wantTokens: []string{"park", "KA-01-HH-1234", "Dark Blue", "car"}},
+		{name: "Single quotes and an empty token", line: `close_slot 2 'wet # paint' ""`, wantTokens: []string{"close_slot", "2", "wet # paint", ""}},
+		{name: "Escaped quote", line: `close_slot 2 "the \"old\" barrier"`, wantTokens: []string{"close_slot", "2", `the "old" barrier`}},
+		{name: "Comment", line: "status # show the carpark", wantTokens: []string{"status"}},
+		{name: "Comment line", line: "# create the carpark", wantTokens: nil},
+		{name: "Hash within a token", line: "park KA#1 White car", wantTokens: []string{"park", "KA#1", "White", "car"}},
+		{name: "Continued", line: `park KA-01-HH-1234 \`, wantTokens: []string{"park", "KA-01-HH-1234"}, wantContinued: true},
+		{name: "Unterminated quote", line: `park KA-01-HH-1234 "Dark Blue car`, wantErr: "Line 0, token 3: unterminated quote"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotTokens, gotContinued, err := tokenize(tt.line)
+			if (err != nil || tt.wantErr != "") && (err == nil || err.Error() != tt.wantErr) {
+				t.Fatalf("tokenize() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(gotTokens, tt.wantTokens) || gotContinued != tt.wantContinued {
+				t.Errorf("tokenize() = %q, %v, want %q, %v", gotTokens, gotContinued, tt.wantTokens, tt.wantContinued)
+			}
+		})
+	}
+}
+
+func Test_commandReader_next(t *testing.T) {
+	input := "# Carpark\n\nCREATE_PARKING_LOT 6\npark KA-01-HH-1234 \\\n  \"Dark Blue\" \\\n  Car\nStatus\nclose_slot 2 \"broken\n\nexit"
+	commands := newCommandReader(bufio.NewScanner(strings.NewReader(input)))
+	want := []struct {
+		tokens []string
+		err    string
+		start  int
+	}{
+		{tokens: []string{"create_parking_lot", "6"}, start: 3},
+		{tokens: []string{"park", "KA-01-HH-1234", "Dark Blue", "Car"}, start: 4},
+		{tokens: []string{"status"}, start: 7},
+		{err: "Line 8, token 3: unterminated quote", start: 8},
+		{tokens: []string{"exit"}, start: 10},
+	}
+	for _, command := range want {
+		tokens, err := commands.next()
+		if (err != nil || command.err != "") && (err == nil || err.Error() != command.err) {
+			t.Fatalf("commandReader.next() error = %v, want %v", err, command.err)
+		}
+		if !reflect.DeepEqual(tokens, command.tokens) || commands.start != command.start {
+			t.Errorf("commandReader.next() = %q on line %v, want %q on line %v", tokens, commands.start, command.tokens, command.start)
+		}
+	}
+	if _, err := commands.next(); err != io.EOF {
+		t.Errorf("commandReader.next() error = %v, want %v", err, io.EOF)
+	}
+}
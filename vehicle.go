@@ -1,19 +1,35 @@
 package main
 
-//Vehicle represents car, motorcycle, and bus
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+//Vehicle represents a vehicle of any type in the vehicle registry
 type Vehicle interface {
 	getRegistration() *string
 	getColour() *string
 	getSlot() *int
+	getArrival() *time.Time
+	getPermits() *attributes
 	getSlotsNeeded() int
+	getTariff() string
 	getType() string
 }
 
 type baseVehicle struct {
-	name         string //Type of vehicle
-	registration string //Registration number of car
-	colour       string //Colour of car
-	slot         int    //Slot number in which the motorcycle is parked
+	name         string     //Type of vehicle
+	registration string     //Registration number of car
+	colour       string     //Colour of car
+	slot         int        //Slot number in which the motorcycle is parked
+	arrival      time.Time  //Time at which the vehicle was parked
+	permits      attributes //Slot attributes the vehicle holds a permit for or needs
+	slots        int        //Number of adjacent slots the vehicle occupies
+	tariff       string     //Tariff class the vehicle is charged under
 }
 
 func (basevehicle *baseVehicle) fit() bool {
@@ -32,48 +48,164 @@ func (basevehicle *baseVehicle) getSlot() *int {
 	return &basevehicle.slot
 }
 
-func (basevehicle *baseVehicle) getType() string {
-	return basevehicle.name
-}
-
-// Car represents the properties of a car
-type Car struct {
-	baseVehicle
-}
-
-func (car *Car) getSlotsNeeded() int {
-	return 2
+func (basevehicle *baseVehicle) getArrival() *time.Time {
+	return &basevehicle.arrival
 }
 
-//NewCar is a car constructor function
-func NewCar() *Car {
-	return &Car{baseVehicle: baseVehicle{name: "Car"}}
+func (basevehicle *baseVehicle) getPermits() *attributes {
+	return &basevehicle.permits
 }
 
-//Motorcycle represents the properties of a motorcycle
-type Motorcycle struct {
-	baseVehicle
+func (basevehicle *baseVehicle) getSlotsNeeded() int {
+	return basevehicle.slots
 }
 
-func (motorcycle *Motorcycle) getSlotsNeeded() int {
-	return 1
+func (basevehicle *baseVehicle) getTariff() string {
+	return basevehicle.tariff
 }
 
-//NewMotorcycle is a motorcycle constructor function
-func NewMotorcycle() *Motorcycle {
-	return &Motorcycle{baseVehicle: baseVehicle{name: "Motorcycle"}}
-}
-
-//Bus represents the properties of a bus
-type Bus struct {
-	baseVehicle
-}
-
-func (bus *Bus) getSlotsNeeded() int {
-	return 3
+func (basevehicle *baseVehicle) getType() string {
+	return basevehicle.name
 }
 
-//NewBus is a bus constructor function
-func NewBus() *Bus {
-	return &Bus{baseVehicle: baseVehicle{name: "Bus"}}
+//vehicleClass describes a type of vehicle
+type vehicleClass struct {
+	Name   string   `json:"name"`   //Name of the type as used in commands, in lower case
+	Slots  int      `json:"slots"`  //Number of adjacent slots a vehicle of the type occupies
+	Zones  []string `json:"zones"`  //Zones besides its own in which the type may park, general when none are given
+	Tariff string   `json:"tariff"` //Tariff charged for the type, defaults to the name in title case
+}
+
+//vehicle constructs a vehicle of the type
+func (class *vehicleClass) vehicle(registration string, colour string) Vehicle {
+	return &baseVehicle{name: strings.Title(class.Name), registration: registration, colour: colour,
+		slots: class.Slots, tariff: class.tariff()}
+}
+
+//tariff returns the name of the tariff charged for the type
+func (class *vehicleClass) tariff() string {
+	if class.Tariff != "" {
+		return class.Tariff
+	}
+	return strings.Title(class.Name)
+}
+
+//zones returns the zones besides its own in which the type may park
+func (class *vehicleClass) zones() []string {
+	if len(class.Zones) > 0 {
+		return class.Zones
+	}
+	return []string{zoneGeneral}
+}
+
+//vehicleRegistry holds the types of vehicle the carpark parks, keyed by name
+type vehicleRegistry map[string]*vehicleClass
+
+//defaultVehicleTypes holds the built-in types of vehicle
+var defaultVehicleTypes = vehicleRegistry{
+	"motorcycle": {Name: "motorcycle", Slots: 1},
+	"car":        {Name: "car", Slots: 2},
+	"bus":        {Name: "bus", Slots: 3},
+}
+
+//unknownTypeError reports a vehicle type missing from the vehicle registry
+type unknownTypeError struct {
+	name  string   //Type asked for
+	known []string //Types in the registry
+}
+
+func (err *unknownTypeError) Error() string {
+	return fmt.Sprintf("Unknown vehicle type %v, expected one of %v", err.name, strings.Join(err.known, ", "))
+}
+
+//lookup finds a type of vehicle by name, ignoring case
+func (registry vehicleRegistry) lookup(name string) (*vehicleClass, error) {
+	if class, ok := registry[strings.ToLower(name)]; ok {
+		return class, nil
+	}
+	return nil, &unknownTypeError{name: name, known: registry.names()}
+}
+
+//names lists the types of vehicle in alphabetical order
+func (registry vehicleRegistry) names() []string {
+	var names []string
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+//loadVehicleTypes reads types of vehicle from a JSON file, keeping the built-in types it does not redefine
+func loadVehicleTypes(fileName string) (vehicleRegistry, error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var config struct {
+		Vehicles []*vehicleClass `json:"vehicles"`
+	}
+	if err := json.NewDecoder(file).Decode(&config); err != nil {
+		return nil, fmt.Errorf("Invalid vehicle file %v: %v", fileName, err)
+	}
+	registry := make(vehicleRegistry)
+	for name, class := range defaultVehicleTypes {
+		registry[name] = class
+	}
+	defined := make(map[string]bool)
+	for _, class := range config.Vehicles {
+		class.Name = strings.ToLower(class.Name)
+		if defined[class.Name] {
+			return nil, fmt.Errorf("Invalid vehicle file %v: vehicle type %q defined twice", fileName, class.Name)
+		}
+		defined[class.Name] = true
+		registry[class.Name] = class
+	}
+	if err := registry.validate(); err != nil {
+		return nil, fmt.Errorf("Invalid vehicle file %v: %v", fileName, err)
+	}
+	return registry, nil
+}
+
+//validate checks the name, size, and zones of every type of vehicle
+func (registry vehicleRegistry) validate() error {
+	for _, name := range registry.names() {
+		class := registry[name]
+		switch {
+		case name == "" || strings.ContainsAny(name, " \t,"):
+			return fmt.Errorf("vehicle type %q must be a single word", name)
+		case name == zoneGeneral:
+			return fmt.Errorf("vehicle type %q is the name of the general zone", name)
+		case class.Slots <= 0:
+			return fmt.Errorf("vehicle type %q must occupy at least one slot", name)
+		}
+		for ii, zone := range class.Zones {
+			class.Zones[ii] = strings.ToLower(zone)
+			if _, ok := registry[class.Zones[ii]]; !ok && class.Zones[ii] != zoneGeneral {
+				return fmt.Errorf("vehicle type %q may not park in unknown zone %q", name, zone)
+			}
+		}
+	}
+	return nil
+}
+
+//priced checks that the tariff plan charges every type of vehicle
+func (registry vehicleRegistry) priced(plan *tariffPlan) error {
+	for _, name := range registry.names() {
+		if _, ok := plan.Tariffs[registry[name].tariff()]; !ok {
+			return fmt.Errorf("Vehicle type %v has no %v tariff", name, registry[name].tariff())
+		}
+	}
+	return nil
+}
+
+//newVehicle constructs a vehicle of the named type, or returns nil for an unknown type
+func (registry vehicleRegistry) newVehicle(vehicleType string, registration string, colour string) Vehicle {
+	class, err := registry.lookup(vehicleType)
+	if err != nil {
+		return nil
+	}
+	return class.vehicle(registration, colour)
 }
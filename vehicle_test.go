@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func Test_loadVehicleTypes(t *testing.T) {
+	dir, err := ioutil.TempDir("", "vehicles")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	tests := []struct {
+		name    string
+		content string
+		want    vehicleRegistry
+		wantErr bool
+	}{
+		{name: "Add a vehicle type and resize another",
+			content: `{"vehicles": [{"name": "Van", "slots": 2, "zones": ["Car"], "tariff": "Car"}, {"name": "bus", "slots": 4}]}`,
+			want: vehicleRegistry{
+				"motorcycle": defaultVehicleTypes["motorcycle"],
+				"car":        defaultVehicleTypes["car"],
+				"bus":        {Name: "bus", Slots: 4},
+				"van":        {Name: "van", Slots: 2, Zones: []string{"car"}, Tariff: "Car"},
+			},
+			wantErr: false,
+		},
+		{name: "No slots",
+			content: `{"vehicles": [{"name": "truck"}]}`,
+			wantErr: true,
+		},
+		{name: "Unknown zone",
+			content: `{"vehicles": [{"name": "truck", "slots": 4, "zones": ["lorry"]}]}`,
+			wantErr: true,
+		},
+		{name: "Defined twice",
+			content: `{"vehicles": [{"name": "truck", "slots": 4}, {"name": "Truck", "slots": 5}]}`,
+			wantErr: true,
+		},
+		{name: "Name of the general zone",
+			content: `{"vehicles": [{"name": "general", "slots": 1}]}`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fileName := filepath.Join(dir, "vehicles.json")
+			if err := ioutil.WriteFile(fileName, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			got, err := loadVehicleTypes(fileName)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("loadVehicleTypes() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("loadVehicleTypes() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCarpark_insertConfiguredVehicle(t *testing.T) {
+	vehicleTypes := vehicleRegistry{
+		"car": defaultVehicleTypes["car"],
+		"van": {Name: "van", Slots: 2, Zones: []string{"car"}, Tariff: "Car"},
+	}
+	if _, err := vehicleTypes.lookup("truck"); err == nil || err.Error() != "Unknown vehicle type truck, expected one of car, van" {
+		t.Errorf("vehicleRegistry.lookup() error = %v", err)
+	}
+	if vehicleTypes.newVehicle("motorcycle", "KA-01-MM-0001", "Black") != nil {
+		t.Errorf("vehicleRegistry.newVehicle() built a vehicle of a type missing from the registry")
+	}
+
+	carpark := &Carpark{clock: fixedClock(testTime), vehicleTypes: vehicleTypes}
+	carpark.init(nil, 8)
+	carpark.setZone("van", 1, 2)
+	carpark.setZone("car", 3, 4)
+	for ii, want := range []int{1, 3, 0} {
+		got, _, err := carpark.insertCar(vehicleTypes.newVehicle("Van", fmt.Sprintf("KA-01-VV-000%v", ii), "White"))
+		if want == 0 && err != errFull {
+			t.Errorf("Carpark.insertCar() error = %v, want %v", err, errFull)
+		}
+		if got != want {
+			t.Errorf("Carpark.insertCar() = %v, want %v", got, want)
+		}
+	}
+
+	carpark.clock = fixedClock(testTime.Add(90 * time.Minute))
+	receipt, err := carpark.removeCar(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := defaultTariffs.fee("Car", testTime, testTime.Add(90*time.Minute)); receipt.fee != want || receipt.vehicle.getType() != "Van" {
+		t.Errorf("Carpark.removeCar() = %v %v, want Van charged %v", receipt.vehicle.getType(), receipt.fee, want)
+	}
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"errors"
+	"strings"
+)
+
+//zoneGeneral is the type of slot accepting any vehicle, and of every slot unless set otherwise.
+//Every other type of slot is named after the vehicle type it is built for.
+const zoneGeneral = "general"
+
+//Errors reported when setting slot zones
+var (
+	errUnknownZone  = errors.New("Unknown slot zone")
+	errZoneOccupied = errors.New("Sorry, a vehicle parked in those slots cannot use that zone")
+	errSlotRange    = errors.New("Invalid range of slots")
+)
+
+//Check whether the name is a type of slot
+func (carpark *Carpark) validZone(zone string) bool {
+	_, ok := carpark.types()[zone]
+	return ok || zone == zoneGeneral
+}
+
+//Set the type of the slots from 'firstSlot' to 'lastSlot', refusing when a vehicle parked there could no longer use them,
+//admit waiting vehicles which may now park, and return the vehicles admitted
+func (carpark *Carpark) setZone(zone string, firstSlot int, lastSlot int) ([]Vehicle, error) {
+	carpark.mu.Lock()
+	defer carpark.mu.Unlock()
+
+	if err := carpark.initStatus(); err != nil {
+		return nil, err
+	}
+	zone = strings.ToLower(zone)
+	if !carpark.validZone(zone) {
+		return nil, errUnknownZone
+	}
+	if firstSlot > lastSlot || carpark.floorOf(firstSlot) == nil || carpark.floorOf(lastSlot) == nil {
+		return nil, errSlotRange
+	}
+	for slotNo, vehicle := range carpark.Map {
+		last := slotNo + vehicle.getSlotsNeeded() - 1
+		if slotNo <= lastSlot && last >= firstSlot && !carpark.accepts(vehicle, zone) {
+			return nil, errZoneOccupied
+		}
+	}
+	if err := carpark.record(&event{Type: eventZone, Time: carpark.now(), Zone: zone, Slot: firstSlot, LastSlot: lastSlot}); err != nil {
+		return nil, err
+	}
+	carpark.zone(zone, firstSlot, lastSlot)
+	carpark.changed()
+	return carpark.admit(), nil
+}
+
+//Let a vehicle type park in the given zones once the slots of its own type and general slots are taken,
+//or stop it overflowing when no zones are given, admit waiting vehicles which may now park, and return the vehicles admitted
+func (carpark *Carpark) setOverflow(vehicleType string, zones []string) ([]Vehicle, error) {
+	carpark.mu.Lock()
+	defer carpark.mu.Unlock()
+
+	if err := carpark.initStatus(); err != nil {
+		return nil, err
+	}
+	class, err := carpark.types().lookup(vehicleType)
+	if err != nil {
+		return nil, err
+	}
+	vehicleType = class.Name
+	var overflow []string
+	for _, zone := range zones {
+		zone = strings.ToLower(zone)
+		if !carpark.validZone(zone) {
+			return nil, errUnknownZone
+		}
+		overflow = append(overflow, zone)
+	}
+	if err := carpark.record(&event{Type: eventOverflow, Time: carpark.now(), Vehicle: vehicleType, Zones: overflow}); err != nil {
+		return nil, err
+	}
+	carpark.overflowInto(vehicleType, overflow)
+	carpark.changed()
+	return carpark.admit(), nil
+}
+
+//The following helpers expect the caller to hold the carpark lock
+
+//Set the type of the slots from 'firstSlot' to 'lastSlot'
+func (carpark *Carpark) zone(zone string, firstSlot int, lastSlot int) {
+	if carpark.zones == nil {
+		carpark.zones = make(map[int]string)
+	}
+	for slotNo := firstSlot; slotNo <= lastSlot; slotNo++ {
+		if zone == zoneGeneral {
+			delete(carpark.zones, slotNo)
+		} else {
+			carpark.zones[slotNo] = zone
+		}
+	}
+}
+
+//Set the zones into which a vehicle type overflows
+func (carpark *Carpark) overflowInto(vehicleType string, zones []string) {
+	if carpark.overflow == nil {
+		carpark.overflow = make(map[string][]string)
+	}
+	if len(zones) == 0 {
+		delete(carpark.overflow, vehicleType)
+	} else {
+		carpark.overflow[vehicleType] = zones
+	}
+}
+
+//Return the type of a slot
+func (carpark *Carpark) zoneOf(slotNo int) string {
+	if zone, ok := carpark.zones[slotNo]; ok {
+		return zone
+	}
+	return zoneGeneral
+}
+
+//List the sets of zones a vehicle may park in, from the most preferred: slots of its own type,
+//then the zones its type allows as well, general slots unless configured otherwise, then the zones it overflows into as well
+func (carpark *Carpark) zoneTiers(vehicle Vehicle) []map[string]bool {
+	vehicleType := strings.ToLower(vehicle.getType())
+	allowed := []string{zoneGeneral}
+	if class, err := carpark.types().lookup(vehicleType); err == nil {
+		allowed = class.zones()
+	}
+	tier := func(zones ...[]string) map[string]bool {
+		tier := map[string]bool{vehicleType: true}
+		for _, list := range zones {
+			for _, zone := range list {
+				tier[zone] = true
+			}
+		}
+		return tier
+	}
+	tiers := []map[string]bool{tier(), tier(allowed)}
+	if overflow := carpark.overflow[vehicleType]; len(overflow) > 0 {
+		tiers = append(tiers, tier(allowed, overflow))
+	}
+	return tiers
+}
+
+//Check whether a vehicle may park in a zone
+func (carpark *Carpark) accepts(vehicle Vehicle, zone string) bool {
+	tiers := carpark.zoneTiers(vehicle)
+	return tiers[len(tiers)-1][zone]
+}
+
+//Check whether every slot of a run lies in the given zones
+func (carpark *Carpark) within(slotNo int, slots int, zones map[string]bool) bool {
+	for ii := 0; ii < slots; ii++ {
+		if !zones[carpark.zoneOf(slotNo+ii)] {
+			return false
+		}
+	}
+	return true
+}
+
+//List the free positions in which a vehicle may park, apart from those excluded, keeping only those
+//in the most preferred zones available and then those best suiting its needs
+func (carpark *Carpark) positions(vehicle Vehicle, excluded func(slotNo int, slots int) bool) []candidate {
+	var free []candidate
+	for _, floor := range carpark.floors {
+		free = append(free, floor.candidates(vehicle.getSlotsNeeded())...)
+	}
+	return carpark.suitable(vehicle, free, excluded)
+}
+
+//Narrow the positions to those in which a vehicle may park, apart from those excluded, keeping only those
+//in the most preferred zones available and then those best suiting its needs
+func (carpark *Carpark) suitable(vehicle Vehicle, free []candidate, excluded func(slotNo int, slots int) bool) []candidate {
+	for _, zones := range carpark.zoneTiers(vehicle) {
+		var candidates []candidate
+		for _, candidate := range free {
+			if carpark.within(candidate.slot, candidate.slots, zones) && carpark.permitted(vehicle, candidate.slot, candidate.slots) &&
+				!excluded(candidate.slot, candidate.slots) {
+				candidates = append(candidates, candidate)
+			}
+		}
+		if len(candidates) > 0 {
+			return carpark.prefer(vehicle, candidates)
+		}
+	}
+	return nil
+}
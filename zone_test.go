@@ -0,0 +1,78 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestCarpark_insertCarInZones(t *testing.T) {
+	carpark := &Carpark{}
+	carpark.init(nil, 8)
+	if _, err := carpark.setZone("motorcycle", 1, 2); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := carpark.setZone("Bus", 6, 8); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		vehicle  Vehicle
+		overflow []string
+		want     int
+		wantErr  error
+	}{
+		{name: "Car takes general slots", vehicle: defaultVehicleTypes.newVehicle("car", "KA-01-HH-0001", "White"), want: 3},
+		{name: "Motorcycle takes a motorcycle bay", vehicle: defaultVehicleTypes.newVehicle("motorcycle", "KA-01-MM-0001", "Black"), want: 1},
+		{name: "Motorcycle takes the last motorcycle bay", vehicle: defaultVehicleTypes.newVehicle("motorcycle", "KA-01-MM-0002", "Black"), want: 2},
+		{name: "Motorcycle falls back to a general slot", vehicle: defaultVehicleTypes.newVehicle("motorcycle", "KA-01-MM-0003", "Black"), want: 5},
+		{name: "Motorcycle kept out of bus bays", vehicle: defaultVehicleTypes.newVehicle("motorcycle", "KA-01-MM-0004", "Black"), wantErr: errFull},
+		{name: "Motorcycle overflows into a bus bay", vehicle: defaultVehicleTypes.newVehicle("motorcycle", "KA-01-MM-0004", "Black"), overflow: []string{"bus"}, want: 6},
+		{name: "Bus bays too short for a bus", vehicle: defaultVehicleTypes.newVehicle("bus", "KA-01-BB-0001", "Red"), wantErr: errFull},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.overflow != nil {
+				if _, err := carpark.setOverflow("motorcycle", tt.overflow); err != nil {
+					t.Fatal(err)
+				}
+			}
+			got, _, err := carpark.insertCar(tt.vehicle)
+			if err != tt.wantErr {
+				t.Fatalf("Carpark.insertCar() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Carpark.insertCar() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCarpark_setZone(t *testing.T) {
+	tests := []struct {
+		name      string
+		zone      string
+		firstSlot int
+		lastSlot  int
+		wantErr   error
+	}{
+		{name: "Car bays", zone: "car", firstSlot: 1, lastSlot: 4},
+		{name: "General slots", zone: "general", firstSlot: 5, lastSlot: 6},
+		{name: "Parked car cannot use motorcycle bays", zone: "motorcycle", firstSlot: 2, lastSlot: 3, wantErr: errZoneOccupied},
+		{name: "Unknown zone", zone: "truck", firstSlot: 1, lastSlot: 2, wantErr: errUnknownZone},
+		{name: "Beyond the carpark", zone: "bus", firstSlot: 5, lastSlot: 7, wantErr: errSlotRange},
+		{name: "Reversed range", zone: "bus", firstSlot: 4, lastSlot: 3, wantErr: errSlotRange},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			carpark := &Carpark{}
+			carpark.init(nil, 6)
+			carpark.insertCar(defaultVehicleTypes.newVehicle("car", "KA-01-HH-0001", "White"))
+			if _, err := carpark.setZone(tt.zone, tt.firstSlot, tt.lastSlot); err != tt.wantErr {
+				t.Errorf("Carpark.setZone() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && carpark.zoneOf(tt.firstSlot) != tt.zone {
+				t.Errorf("Carpark.zoneOf() = %v, want %v", carpark.zoneOf(tt.firstSlot), tt.zone)
+			}
+		})
+	}
+}